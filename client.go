@@ -1,8 +1,18 @@
 package rtpengine
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 	"net"
+	"regexp"
+	"runtime/debug"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/rs/zerolog"
@@ -11,12 +21,43 @@ import (
 
 type Client struct {
 	*Engine
-	url     string
-	port    int
-	log     zerolog.Logger
-	timeout time.Duration
+	url          string
+	port         int
+	log          zerolog.Logger
+	timeout      time.Duration
+	ctx          context.Context
+	done         chan struct{}
+	closeOnce    sync.Once
+	closeErr     error
+	mu           sync.Mutex
+	poolSize     int
+	pool         chan net.Conn
+	metrics      MetricsCollector
+	tracer       *tracer
+	transport    Transport
+	legacyFlags  bool
+	stats        clientStats
+	versaoMu     sync.Mutex
+	versao       string
+	observer     ClientObserver
+	hostname     string
+	dnsName      string
+	keepalive    time.Duration
+	keepRaw      bool
+	resetTimeout bool
+	ultimoUso    atomic.Int64
+	family       AddressFamily
+	rastrear     bool
+	sessoesMu    sync.Mutex
+	sessoes      map[string]struct{}
+	ofertados    map[string]struct{}
+	retryPolicy  RetryPolicy
+	retryCmds    []TipoComandos
 }
 
+// Função chamada ao final de cada comando com o cookie, o comando, a resposta (nil em falha de transporte), a latencia e o erro
+type ClientObserver func(cookie string, comando *RequestRtp, resposta *ResponseRtp, latency time.Duration, err error)
+
 type ClientOption func(c *Client) error
 
 func NewClient(rtpengine *Engine, options ...ClientOption) (*Client, error) {
@@ -26,6 +67,7 @@ func NewClient(rtpengine *Engine, options ...ClientOption) (*Client, error) {
 		port:    rtpengine.GetPort(),
 		log:     log.Logger.With().Str("Client", "RTPEngine").Logger(),
 		timeout: 10 * time.Second,
+		done:    make(chan struct{}),
 	}
 
 	for _, o := range options {
@@ -34,17 +76,104 @@ func NewClient(rtpengine *Engine, options ...ClientOption) (*Client, error) {
 		}
 	}
 
+	if c.dnsName != "" {
+		ip, err := c.resolverDns(c.dnsName)
+		if err != nil {
+			return nil, err
+		}
+		c.url = ip.String()
+	}
+
+	if c.hostname != "" {
+		ip, err := c.resolverHostname(c.hostname)
+		if err != nil {
+			return nil, err
+		}
+		c.url = ip.String()
+	}
+
 	if c.url != "" && c.url != "<nil>" {
 		c.ip = net.ParseIP(c.url)
 	}
 
-	if _, err := c.Engine.Conn(); err != nil {
+	if c.transport != nil {
+		if c.poolSize > 0 {
+			return nil, fmt.Errorf("WithClientTransport não pode ser usado com o pool de conexões")
+		}
+		c.con = &transportConn{Transport: c.transport}
+	} else if _, err := c.Engine.Conn(); err != nil {
 		c.log.Warn().Msg("Erro ao conectar com o proxy rtpengine " + err.Error())
 	}
 
+	if c.poolSize > 0 {
+		c.pool = make(chan net.Conn, c.poolSize)
+		c.pool <- c.con
+		for i := 1; i < c.poolSize; i++ {
+			conn, err := c.dial()
+			if err != nil {
+				c.log.Warn().Msg("Erro ao conectar com o proxy rtpengine " + err.Error())
+			}
+			c.pool <- conn
+		}
+	}
+
+	if c.ctx != nil {
+		go c.watchContext()
+	}
+
+	if c.keepalive > 0 {
+		c.ultimoUso.Store(time.Now().UnixNano())
+		go c.watchKeepalive()
+	}
+
 	return c, nil
 }
 
+// Fecha a conexão quando o contexto base do client for cancelado
+func (c *Client) watchContext() {
+	select {
+	case <-c.ctx.Done():
+		c.log.Debug().Msg("Contexto cancelado fechando a conexão " + c.ctx.Err().Error())
+		c.Close()
+	case <-c.done:
+	}
+}
+
+// Resolve os endereços A e AAAA do hostname e retorna o primeiro da familia preferida
+// sem familia definida ou sem endereço da familia preferida retorna o primeiro endereço resolvido
+func (c *Client) resolverHostname(hostname string) (net.IP, error) {
+	resolver := net.DefaultResolver
+	if c.dns != nil {
+		resolver = c.dns
+	}
+
+	ips, err := resolver.LookupIP(context.TODO(), "ip", hostname)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao resolver o hostname %s: %w", hostname, err)
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("hostname %s sem endereço", hostname)
+	}
+
+	for _, ip := range ips {
+		if addressFamilyIP(ip) == c.family {
+			return ip, nil
+		}
+	}
+
+	// sem familia definida o endereço da outra familia e usado na conexão dupla
+	if c.family == "" {
+		for _, ip := range ips[1:] {
+			if addressFamilyIP(ip) != addressFamilyIP(ips[0]) {
+				c.ipAlternativo = ip
+				break
+			}
+		}
+	}
+	return ips[0], nil
+}
+
 // WithClientPort Permite definir a porta padrão do client
 func WithClientPort(port int) ClientOption {
 	return func(s *Client) error {
@@ -54,37 +183,81 @@ func WithClientPort(port int) ClientOption {
 	}
 }
 
-// WithClientHostname Permite definir o nome do host padrão do client resolve o endereço ipv4 da maquina local.
+// WithClientHostname Permite definir o nome do host padrão do client resolve o endereço ipv4 ou ipv6 do host.
+// a resolução acontece na criação do client respeitando a familia definida em WithClientAddressFamily
 func WithClientHostname(hostname string) ClientOption {
 	return func(s *Client) error {
-		lookup, err := net.ResolveIPAddr("ip4", hostname)
-		if err != nil {
-			s.log.Warn().Msg("Erro resolver name")
+		if hostname == "" {
+			return errors.New("hostname vazio")
+		}
+		s.hostname = hostname
+		return nil
+	}
+}
+
+// WithClientAddressFamily Permite definir a familia de endereço preferida na resolução do hostname
+func WithClientAddressFamily(family AddressFamily) ClientOption {
+	return func(s *Client) error {
+		if family != AddressFamilyIP4 && family != AddressFamilyIP6 {
+			return fmt.Errorf("familia de endereço %s invalida", family)
 		}
-		s.ip = lookup.IP
+		s.family = family
 		return nil
 	}
 }
 
 // WithClientDns Permite definir o dns do serviço do rtpengine a função resolve o ip do serviço.
+// a resolução acontece na criação do client usando o servidor de WithClientResolver ou 8.8.8.8:53, o primeiro ipv4 resolvido e usado
 func WithClientDns(dns string) ClientOption {
 	return func(s *Client) error {
-		domain := &net.Resolver{
-			PreferGo:     false,
-			StrictErrors: false,
-			Dial: func(ctx context.Context, network string, address string) (net.Conn, error) {
-				return net.Dial("udp", "8.8.8.8:53")
-			},
+		if dns == "" {
+			return errors.New("dns vazio")
 		}
-		s.dns = domain
-		url, _ := s.dns.LookupIP(context.TODO(), "ip4", dns)
-		for _, resolver := range url {
-			s.url = resolver.String()
+		s.dnsName = dns
+		return nil
+	}
+}
+
+// WithClientResolver Permite definir o endereço host:porta do servidor dns usado na resolução do client
+func WithClientResolver(address string) ClientOption {
+	return func(s *Client) error {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			return fmt.Errorf("endereço do resolver %s invalido: %w", address, err)
 		}
+		s.dns = newResolver(address)
 		return nil
 	}
 }
 
+// Resolver que envia as consultas para o servidor dns informado
+func newResolver(address string) *net.Resolver {
+	return &net.Resolver{
+		PreferGo:     true,
+		StrictErrors: false,
+		Dial: func(ctx context.Context, network string, _ string) (net.Conn, error) {
+			var d net.Dialer
+			return d.DialContext(ctx, network, address)
+		},
+	}
+}
+
+// Resolve o ipv4 do serviço do rtpengine e retorna o primeiro endereço
+func (c *Client) resolverDns(dns string) (net.IP, error) {
+	if c.dns == nil {
+		c.dns = newResolver("8.8.8.8:53")
+	}
+
+	ips, err := c.dns.LookupIP(context.TODO(), "ip4", dns)
+	if err != nil {
+		return nil, fmt.Errorf("erro ao resolver o dns %s: %w", dns, err)
+	}
+
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("dns %s sem endereço ipv4", dns)
+	}
+	return ips[0], nil
+}
+
 // WithClientPort Permite definir o IP padrão do client
 func WithClientIP(host string) ClientOption {
 	return func(s *Client) error {
@@ -101,51 +274,1150 @@ func WithClientProto(proto string) ClientOption {
 	}
 }
 
-// Fechar conexão aberta.
-func (s *Client) Close() error {
-	return s.con.Close()
+// WithClientConnectTimeout Permite definir o tempo maximo para abrir a conexão com o rtpengine, separado do timeout de leitura
+func WithClientConnectTimeout(d time.Duration) ClientOption {
+	return func(s *Client) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout de conexão %s invalido", d)
+		}
+		s.Engine.connectTimeout = d
+		return nil
+	}
+}
+
+// WithClientContext Permite definir o contexto base do client, a conexão e fechada quando o contexto for cancelado
+func WithClientContext(ctx context.Context) ClientOption {
+	return func(s *Client) error {
+		s.ctx = ctx
+		return nil
+	}
+}
+
+// WithClientPoolSize Permite definir a quantidade de conexões mantidas abertas com o rtpengine
+// cada comando usa uma conexão exclusiva do pool e a devolve ao final
+func WithClientPoolSize(n int) ClientOption {
+	return func(s *Client) error {
+		if n < 1 {
+			return fmt.Errorf("tamanho do pool %d invalido", n)
+		}
+		s.poolSize = n
+		return nil
+	}
+}
+
+// WithClientObserver Permite definir a função chamada ao final de cada comando, inclusive em timeout ou falha de transporte
+func WithClientObserver(observer ClientObserver) ClientOption {
+	return func(s *Client) error {
+		s.observer = observer
+		return nil
+	}
+}
+
+// Definir o nivel de log do client conforme o zerolog: Trace=-1, Debug=0, Info=1, Warn=2, Error=3, Fatal=4, Panic=5, NoLevel=6, Disabled=7
+func (s *Client) SetLogLevel(level int8) error {
+	if level < int8(zerolog.TraceLevel) || level > int8(zerolog.Disabled) {
+		return fmt.Errorf("nivel de log %d invalido", level)
+	}
+	s.log = s.log.Level(zerolog.Level(level))
+	return nil
+}
+
+// WithClientKeepalive Permite definir o intervalo do ping enviado nas conexões ociosas, a conexão com falha e reaberta
+func WithClientKeepalive(interval time.Duration) ClientOption {
+	return func(s *Client) error {
+		if interval <= 0 {
+			return fmt.Errorf("intervalo do keepalive %s invalido", interval)
+		}
+		s.keepalive = interval
+		return nil
+	}
+}
+
+// WithClientKeepRaw Permite manter na resposta o bencode recebido do rtpengine acessivel pelo Raw
+func WithClientKeepRaw() ClientOption {
+	return func(s *Client) error {
+		s.keepRaw = true
+		return nil
+	}
+}
+
+// Envia o ping nas conexões ociosas a cada intervalo do keepalive ate o client ser fechado
+func (c *Client) watchKeepalive() {
+	ticker := time.NewTicker(c.keepalive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if time.Since(time.Unix(0, c.ultimoUso.Load())) >= c.keepalive {
+				c.keepalivePing()
+			}
+		case <-c.done:
+			return
+		}
+	}
 }
 
-func (c *Client) NewComando(comando *RequestRtp) *ResponseRtp {
+// Envia o ping em cada conexão livre, conexões em uso por um comando não estão ociosas e são ignoradas
+func (c *Client) keepalivePing() {
+	if c.pool == nil {
+		if !c.mu.TryLock() {
+			return
+		}
+		defer c.release(c.con, false)
+
+		if err := c.ping(c.con); err != nil {
+			c.log.Warn().Msg("Keepalive sem resposta, reconectando " + err.Error())
+			if err := c.reconectar(); err != nil {
+				c.log.Warn().Msg("Erro ao conectar com o proxy rtpengine " + err.Error())
+			}
+		}
+		return
+	}
+
+	for i := 0; i < c.poolSize; i++ {
+		var conn net.Conn
+		select {
+		case conn = <-c.pool:
+		default:
+			return
+		}
+
+		if conn == nil {
+			c.pool <- nil
+			continue
+		}
+
+		if err := c.ping(conn); err != nil {
+			c.log.Warn().Msg("Keepalive sem resposta, reconectando " + err.Error())
+			conn.Close()
+			conn, err = c.dial()
+			if err != nil {
+				c.log.Warn().Msg("Erro ao conectar com o proxy rtpengine " + err.Error())
+			} else {
+				c.stats.reconexoes.Add(1)
+			}
+		}
+		c.release(conn, false)
+	}
+}
+
+// Envia o ping na conexão informada e aguarda o pong com o mesmo cookie
+func (c *Client) ping(conn net.Conn) error {
+	if conn == nil {
+		return net.ErrClosed
+	}
+
 	cookie := c.GetCookie()
-	err := c.ComandoNG(cookie, comando)
+	if err := c.enviar(conn, cookie, &RequestRtp{Command: string(Ping)}); err != nil {
+		return err
+	}
+
+	resposta, err := c.receber(conn, cookie, c.timeout)
+	if err != nil {
+		return err
+	}
+
+	if resposta.ResultType() != ResultPong && resposta.ResultType() != ResultOK {
+		return fmt.Errorf("resposta %q inesperada ao ping %s", resposta.Result, resposta.ErrorReason)
+	}
+	return nil
+}
+
+// WithClientResetOnTimeout Permite reabrir a conexão apos o timeout de um comando descartando a resposta atrasada
+func WithClientResetOnTimeout() ClientOption {
+	return func(s *Client) error {
+		s.resetTimeout = true
+		return nil
+	}
+}
+
+// WithClientTrackSessions Registra os call-ids criados pelo offer para serem removidos no Shutdown
+func WithClientTrackSessions() ClientOption {
+	return func(s *Client) error {
+		s.rastrear = true
+		s.sessoes = make(map[string]struct{})
+		return nil
+	}
+}
+
+// Adiciona o call-id no offer aceito e remove no delete da chamada inteira
+func (c *Client) rastrearSessao(comando *RequestRtp, resposta *ResponseRtp) {
+	if (!c.rastrear && c.ofertados == nil) || comando.ParamsOptString == nil || comando.CallId == "" {
+		return
+	}
+
+	// a chamada desconhecida no delete ja não existe no rtpengine
+	ok := resposta.ResultType() == ResultOK
+	c.sessoesMu.Lock()
+	defer c.sessoesMu.Unlock()
+	switch {
+	case comando.Command == string(Offer) && ok:
+		if c.rastrear {
+			c.sessoes[comando.CallId] = struct{}{}
+		}
+		if c.ofertados != nil {
+			c.ofertados[comando.CallId] = struct{}{}
+		}
+	case comando.Command == string(Delete) && comando.ViaBranch == "" && (ok || unknownCall(resposta.ErrorReason)):
+		delete(c.sessoes, comando.CallId)
+		delete(c.ofertados, comando.CallId)
+	}
+}
+
+// WithClientAutoIncrementSDP Adiciona o force-increment-sdp-version nos re-offers de um call-id ja oferecido
+// evita que o endpoint ignore o re-INVITE (hold/resume) com a mesma versão do SDP
+func WithClientAutoIncrementSDP() ClientOption {
+	return func(s *Client) error {
+		s.ofertados = make(map[string]struct{})
+		return nil
+	}
+}
+
+// Adiciona o force-increment-sdp-version no offer de um call-id que ja recebeu offer
+func (c *Client) incrementarSdp(comando *RequestRtp) {
+	if c.ofertados == nil || comando.Command != string(Offer) || comando.ParamsOptString == nil {
+		return
+	}
+
+	c.sessoesMu.Lock()
+	_, reoffer := c.ofertados[comando.CallId]
+	c.sessoesMu.Unlock()
+	if !reoffer {
+		return
+	}
+
+	if comando.ParamsOptStringArray == nil {
+		comando.ParamsOptStringArray = &ParamsOptStringArray{}
+	}
+	addReplace(comando, ForceIncrementSdpVersion)
+}
+
+// Call-ids registrados pelo WithClientTrackSessions que ainda não foram removidos
+func (c *Client) Sessions() []string {
+	c.sessoesMu.Lock()
+	defer c.sessoesMu.Unlock()
+	callIds := make([]string, 0, len(c.sessoes))
+	for callId := range c.sessoes {
+		callIds = append(callIds, callId)
+	}
+	sort.Strings(callIds)
+	return callIds
+}
+
+// Envia o delete de cada sessão registrada pelo WithClientTrackSessions respeitando o prazo do contexto
+// retorna os call-ids que não foram removidos e o erro do contexto ou do ultimo delete com falha
+func (c *Client) Shutdown(ctx context.Context) ([]string, error) {
+	callIds := c.Sessions()
+	var falhas []string
+	var ultimoErr error
+	for i, err := range c.deletarChamadas(ctx, callIds) {
+		if err != nil {
+			falhas, ultimoErr = append(falhas, callIds[i]), err
+		}
+	}
+	return falhas, ultimoErr
+}
+
+// Envia o delete de cada call-id respeitando o contexto, retorna o erro de cada call-id na mesma ordem
+// os call-ids não removidos antes do fim do contexto recebem o erro do contexto, o prazo do contexto limita
+// a espera da resposta de cada delete e nenhum delete e enviado apos o retorno
+func (c *Client) deletarChamadas(ctx context.Context, callIds []string) []error {
+	erros := make([]error, len(callIds))
+	for i, callId := range callIds {
+		if err := ctx.Err(); err != nil {
+			for ; i < len(callIds); i++ {
+				erros[i] = err
+			}
+			break
+		}
+
+		comando := &RequestRtp{Command: string(Delete), ParamsOptString: &ParamsOptString{CallId: callId}}
+		if prazo, ok := ctx.Deadline(); ok && time.Until(prazo) < c.timeout {
+			comando.timeout = max(time.Until(prazo), time.Millisecond)
+		}
+
+		_, erros[i] = c.executarResultado(comando)
+		if erros[i] != nil && ctx.Err() != nil {
+			erros[i] = ctx.Err()
+		}
+	}
+	return erros
+}
+
+// Resultado da remoção em massa com os call-ids removidos e o erro de cada call-id que falhou
+type BulkDeleteResult struct {
+	Deleted []string
+	Failed  map[string]error
+}
+
+// Lista as chamadas do rtpengine e envia o delete das que o match aceitar, ex: chamadas de um tenant apos uma queda
+// o contexto interrompe a remoção e os call-ids restantes ficam em Failed
+func (c *Client) DeleteMatching(ctx context.Context, match func(callId string) bool) (*BulkDeleteResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	request, err := SDPList()
+	if err != nil {
+		return nil, err
+	}
+
+	resposta, err := c.executarResultado(request)
 	if err != nil {
+		return nil, err
+	}
+
+	callIds := make([]string, 0)
+	for _, callId := range resposta.Calls {
+		if match(callId) {
+			callIds = append(callIds, callId)
+		}
+	}
+
+	resultado := &BulkDeleteResult{Deleted: make([]string, 0), Failed: make(map[string]error)}
+	for i, err := range c.deletarChamadas(ctx, callIds) {
+		if err != nil {
+			resultado.Failed[callIds[i]] = err
+			continue
+		}
+		resultado.Deleted = append(resultado.Deleted, callIds[i])
+	}
+	return resultado, ctx.Err()
+}
+
+// Remove as chamadas com o call-id iniciado pelo prefixo
+func (c *Client) DeleteByPrefix(ctx context.Context, prefix string) (*BulkDeleteResult, error) {
+	return c.DeleteMatching(ctx, func(callId string) bool { return strings.HasPrefix(callId, prefix) })
+}
+
+// Politica avaliada apos cada resposta recebida, retorna se o comando deve ser repetido e o intervalo ate a proxima tentativa
+type RetryPolicy func(resp *ResponseRtp, attempt int) (retry bool, delay time.Duration)
+
+// WithClientRetryPolicy Permite repetir o comando conforme a resposta do rtpengine ex: load limit
+// por padrão somente os comandos idempotentes offer, query e ping são repetidos, sem politica nenhum comando e repetido
+func WithClientRetryPolicy(policy RetryPolicy, commands ...TipoComandos) ClientOption {
+	return func(s *Client) error {
+		if policy == nil {
+			return fmt.Errorf("politica de retry vazia")
+		}
+
+		if len(commands) == 0 {
+			commands = []TipoComandos{Offer, Query, Ping}
+		}
+		s.retryPolicy, s.retryCmds = policy, commands
+		return nil
+	}
+}
+
+// WithClientLegacyFlags Envia as flags, replace, rtcp-mux, SDES e OSRTP como string separada por ',' para rtpengine antigos
+func WithClientLegacyFlags() ClientOption {
+	return func(s *Client) error {
+		s.legacyFlags = true
+		return nil
+	}
+}
+
+// Fecha e reabre a conexão descartando as respostas pendentes, no pool as conexões livres são reabertas no proximo uso
+func (c *Client) Reset() error {
+	if c.pool == nil {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		return c.reconectar()
+	}
+
+	select {
+	case <-c.done:
+		return net.ErrClosed
+	default:
+	}
+
+	livres := len(c.pool)
+	for i := 0; i < livres; i++ {
+		if conn := <-c.pool; conn != nil {
+			conn.Close()
+		}
+	}
+
+	for i := 0; i < livres; i++ {
+		c.pool <- nil
+	}
+	return nil
+}
+
+// Fecha e reabre a conexão do client sem pool, deve ser chamado com a conexão bloqueada
+func (c *Client) reconectar() error {
+	select {
+	case <-c.done:
+		return net.ErrClosed
+	default:
+	}
+
+	if c.transport != nil {
 		return nil
 	}
 
-	Resposta, err := c.RespostaNG(cookie)
+	if c.con != nil {
+		c.con.Close()
+		c.con = nil
+	}
+	if _, err := c.Engine.Conn(); err != nil {
+		return err
+	}
+	c.stats.reconexoes.Add(1)
+	return nil
+}
+
+// Verifica se o erro e o timeout da conexão
+func timeoutErr(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// Fechar conexão aberta, sem pool aguarda o comando em andamento para não concorrer com a reconexão
+func (s *Client) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.done)
+		if s.pool == nil {
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if s.con != nil {
+				s.closeErr = s.con.Close()
+			}
+			return
+		}
+
+		// a conexão padrão faz parte do pool, as conexões em uso são fechadas ao serem devolvidas
+		for len(s.pool) > 0 {
+			if conn := <-s.pool; conn != nil {
+				conn.Close()
+			}
+		}
+	})
+	return s.closeErr
+}
 
+func (c *Client) NewComando(comando *RequestRtp) (resposta *ResponseRtp) {
+	logger := c.comandoLogger(comando)
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error().Str("stack", string(debug.Stack())).Msg(fmt.Sprint("Panic ao executar o comando ", r))
+			resposta = &ResponseRtp{Result: string(ResultError), ErrorReason: fmt.Sprint("Panic ao executar o comando: ", r)}
+		}
+	}()
+
+	Resposta, err := c.executar(c.GetCookie(), comando)
 	if err != nil {
+		logger.Warn().Msg("Erro ao executar o comando " + err.Error())
 		return nil
 	}
+
+	if Resposta.HasWarning() {
+		logger.Warn().Str("warning", Resposta.Warning).Msg("Aviso do rtpengine no comando " + comando.Command)
+	}
 	return Resposta
 }
 
+// Envia o comando e repete conforme a politica de retry, cada tentativa usa um novo cookie
+// para não receber a resposta guardada pelo rtpengine
+func (c *Client) executar(cookie string, comando *RequestRtp) (*ResponseRtp, error) {
+	c.incrementarSdp(comando)
+	resposta, err := c.executarComando(cookie, comando)
+	for tentativa := 1; err == nil && c.retryPolicy != nil && c.retryComando(comando); tentativa++ {
+		retry, delay := c.retryPolicy(resposta, tentativa)
+		if !retry {
+			break
+		}
+
+		c.log.Debug().Msg(fmt.Sprintf("Repetindo o comando %s tentativa %d em %s", comando.Command, tentativa, delay))
+		select {
+		case <-time.After(delay):
+		case <-c.done:
+			return resposta, nil
+		}
+		resposta, err = c.executarComando(c.GetCookie(), comando)
+	}
+	return resposta, err
+}
+
+// Verifica se o comando pode ser repetido pela politica de retry
+func (c *Client) retryComando(comando *RequestRtp) bool {
+	for _, cmd := range c.retryCmds {
+		if comando.Command == string(cmd) {
+			return true
+		}
+	}
+	return false
+}
+
+// Envia o comando e aguarda a resposta usando uma conexão exclusiva
+func (c *Client) executarComando(cookie string, comando *RequestRtp) (resposta *ResponseRtp, err error) {
+	inicio := time.Now()
+	if c.metrics != nil {
+		c.metrics.AddInFlight(1)
+	}
+	defer func() { c.registrar(cookie, comando, resposta, err, time.Since(inicio)) }()
+
+	conn, err := c.acquire()
+	if err != nil {
+		return nil, err
+	}
+
+	descartar := true
+	defer func() { c.release(conn, descartar) }()
+
+	if err := c.enviar(conn, cookie, comando); err != nil {
+		if c.pool == nil {
+			return nil, err
+		}
+
+		// conexão do pool quebrada, o comando não foi enviado então tenta novamente em uma nova conexão
+		c.log.Warn().Msg("Erro na conexão do pool, reconectando " + err.Error())
+		conn.Close()
+		if conn, err = c.dial(); err != nil {
+			return nil, err
+		}
+		c.stats.reconexoes.Add(1)
+
+		if err := c.enviar(conn, cookie, comando); err != nil {
+			return nil, err
+		}
+	}
+
+	resposta, err = c.receber(conn, cookie, c.prazo(comando))
+	if err != nil {
+		if c.pool == nil && c.resetTimeout && timeoutErr(err) {
+			// a resposta atrasada chegaria no proximo comando, descarta a conexão
+			if err := c.reconectar(); err != nil {
+				c.log.Warn().Msg("Erro ao conectar com o proxy rtpengine " + err.Error())
+			}
+		}
+		return nil, err
+	}
+
+	descartar = false
+	c.finalizar(comando, resposta)
+	return resposta, nil
+}
+
+// Associa o comando a resposta, aplica o delete não fatal e atualiza as sessões rastreadas
+func (c *Client) finalizar(comando *RequestRtp, resposta *ResponseRtp) {
+	resposta.comando = comando
+	if comando.naoFatal && resposta.ResultType() == ResultError && unknownCall(resposta.ErrorReason) {
+		resposta.Result, resposta.Warning, resposta.ErrorReason = string(ResultOK), resposta.ErrorReason, ""
+	}
+	c.rastrearSessao(comando, resposta)
+}
+
+// Registra o resultado do comando executado
+func (c *Client) registrar(cookie string, comando *RequestRtp, resposta *ResponseRtp, err error, latencia time.Duration) {
+	if c.metrics != nil {
+		c.metrics.AddInFlight(-1)
+		c.metrics.IncCommand(comando.Command, metricsResult(resposta, err))
+		c.metrics.ObserveLatency(comando.Command, latencia)
+	}
+
+	if c.tracer != nil {
+		if err := c.tracer.gravar(cookie, comando, resposta, err, latencia); err != nil {
+			c.log.Warn().Msg("Erro ao gravar o trace " + err.Error())
+		}
+	}
+
+	if c.observer != nil {
+		c.observer(cookie, comando, resposta, latencia, err)
+	}
+}
+
+// Obtem uma conexão exclusiva para o comando
+func (c *Client) acquire() (net.Conn, error) {
+	if c.pool == nil {
+		c.mu.Lock()
+		if c.con == nil {
+			// a conexão falhou ao criar o client ou ao reconectar, tenta abrir novamente
+			if err := c.reconectar(); err != nil {
+				c.mu.Unlock()
+				return nil, err
+			}
+		}
+		return c.con, nil
+	}
+
+	select {
+	case conn := <-c.pool:
+		if conn != nil {
+			return conn, nil
+		}
+
+		conn, err := c.dial()
+		if err != nil {
+			c.pool <- nil
+			return nil, err
+		}
+		c.stats.reconexoes.Add(1)
+		return conn, nil
+	case <-c.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Devolve a conexão, no pool a conexão com falha e descartada e reaberta no proximo uso
+func (c *Client) release(conn net.Conn, descartar bool) {
+	c.ultimoUso.Store(time.Now().UnixNano())
+	if c.pool == nil {
+		c.mu.Unlock()
+		return
+	}
+
+	select {
+	case <-c.done:
+		descartar = true
+	default:
+	}
+
+	if descartar && conn != nil {
+		conn.Close()
+		conn = nil
+	}
+	c.pool <- conn
+}
+
+// Logger do comando com o call-id e o nome do comando no contexto
+func (c *Client) comandoLogger(comando *RequestRtp) zerolog.Logger {
+	logger := c.log.With().Str("command", comando.Command)
+	if comando.ParamsOptString != nil && comando.CallId != "" {
+		logger = logger.Str("call-id", comando.CallId)
+	}
+	return logger.Logger()
+}
+
 // Comando NG formatado em bencode para rtpengine
+//
+// Deprecated: use NewComando, o ComandoNG e o RespostaNG são chamadas separadas e não funcionam com o pool de conexões
 func (c *Client) ComandoNG(cookie string, comando *RequestRtp) error {
-	menssagem, err := EncodeComando(cookie, comando)
+	conn, err := c.acquireNG()
 	if err != nil {
 		return err
 	}
+	defer c.release(conn, false)
+	return c.enviar(conn, cookie, comando)
+}
 
-	c.log.Debug().Msg("cookie: " + cookie + " Comando: " + comando.Command)
+// Resposta do servidor ngcp-rtpengine
+//
+// Deprecated: use NewComando, o ComandoNG e o RespostaNG são chamadas separadas e não funcionam com o pool de conexões
+func (c *Client) RespostaNG(cookie string) (*ResponseRtp, error) {
+	conn, err := c.acquireNG()
+	if err != nil {
+		return nil, err
+	}
+	defer c.release(conn, false)
+	return c.receber(conn, cookie, c.timeout)
+}
 
-	if _, err := c.con.Write(menssagem); err != nil {
+// Obtem a conexão do client sem pool para o ComandoNG e o RespostaNG
+func (c *Client) acquireNG() (net.Conn, error) {
+	if c.pool != nil {
+		return nil, fmt.Errorf("ComandoNG e RespostaNG não podem ser usados com o pool de conexões")
+	}
+	return c.acquire()
+}
+
+// Envia o comando formatado em bencode na conexão informada
+func (c *Client) enviar(conn net.Conn, cookie string, comando *RequestRtp) error {
+	encode := EncodeComando
+	if c.legacyFlags {
+		encode = EncodeComandoLegacy
+	}
+
+	menssagem, err := encode(cookie, comando)
+	if err != nil {
 		return err
 	}
-	return nil
+
+	logger := c.comandoLogger(comando)
+	logger.Debug().Msg("cookie: " + cookie + " Comando: " + comando.Command)
+	if comando.ParamsOptString != nil && comando.ViaBranch != "" && !strings.HasPrefix(comando.ViaBranch, viaBranchMagicCookie) {
+		logger.Warn().Msg("via-branch " + comando.ViaBranch + " sem o magic cookie " + viaBranchMagicCookie)
+	}
+
+	n, err := conn.Write(menssagem)
+	c.stats.escrita(n, err)
+	return err
 }
 
-// Resposta do servidor ngcp-rtpengine
-func (c *Client) RespostaNG(cookie string) (*ResponseRtp, error) {
-	c.con.SetReadDeadline(time.Now().Add(c.timeout))
+// Prazo da resposta do comando, o timeout do comando substitui o padrão do client somente nele
+func (c *Client) prazo(comando *RequestRtp) time.Duration {
+	if comando.timeout > 0 {
+		return comando.timeout
+	}
+	return c.timeout
+}
+
+// Le a resposta do comando na conexão informada
+func (c *Client) receber(conn net.Conn, cookie string, timeout time.Duration) (resposta *ResponseRtp, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.log.Error().Str("stack", string(debug.Stack())).Msg(fmt.Sprint("Panic ao ler a resposta ", r))
+			resposta, err = nil, fmt.Errorf("panic ao ler a resposta: %v", r)
+		}
+	}()
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
 	respostaRaw := make([]byte, 65536)
 
-	_, err := c.con.Read(respostaRaw)
+	n, err := conn.Read(respostaRaw)
+	c.stats.leitura(n, err)
+	if err != nil {
+		return nil, err
+	}
+
+	if resposta, err = DecodeRespostaStrict(cookie, respostaRaw[:n]); err != nil {
+		return nil, err
+	}
+	c.guardarRaw(resposta, respostaRaw[:n])
+	return resposta, nil
+}
+
+// Copia a resposta recebida quando o WithClientKeepRaw estiver ativo
+func (c *Client) guardarRaw(resposta *ResponseRtp, raw []byte) {
+	if c.keepRaw {
+		resposta.raw = append([]byte(nil), raw...)
+	}
+}
+
+// Leitor das respostas do Batch, no TCP as respostas chegam juntas ou divididas entre as leituras
+// então o restante da leitura fica guardado para a proxima resposta, no UDP cada datagrama e uma resposta
+type leitorRespostas struct {
+	conn      net.Conn
+	datagrama bool
+	buf       []byte
+}
+
+func newLeitorRespostas(conn net.Conn) *leitorRespostas {
+	_, datagrama := conn.(net.PacketConn)
+	return &leitorRespostas{conn: conn, datagrama: datagrama}
+}
+
+// Le a proxima resposta da conexão retornando o cookie recebido, usado quando varios comandos aguardam na mesma conexão
+func (c *Client) receberCookie(leitor *leitorRespostas) (cookie string, resposta *ResponseRtp, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			c.log.Error().Str("stack", string(debug.Stack())).Msg(fmt.Sprint("Panic ao ler a resposta ", r))
+			cookie, resposta, err = "", nil, fmt.Errorf("panic ao ler a resposta: %v", r)
+		}
+	}()
+
+	for {
+		n, err := tamanhoMensagem(leitor.buf)
+		if err == nil {
+			mensagem := leitor.buf[:n]
+			leitor.buf = leitor.buf[n:]
+			return c.decodificarCookie(mensagem)
+		}
+
+		if len(leitor.buf) > 0 && (leitor.datagrama || !errors.Is(err, errMensagemIncompleta)) {
+			// não e possivel encontrar o inicio da proxima resposta, o restante e descartado
+			mensagem := leitor.buf
+			leitor.buf = nil
+			cookie, resposta, _ := c.decodificarCookie(mensagem)
+			return cookie, resposta, nil
+		}
+
+		leitor.conn.SetReadDeadline(time.Now().Add(c.timeout))
+		respostaRaw := make([]byte, 65536)
+		n, err = leitor.conn.Read(respostaRaw)
+		c.stats.leitura(n, err)
+		if err != nil {
+			return "", nil, err
+		}
+		leitor.buf = append(leitor.buf, respostaRaw[:n]...)
+	}
+}
+
+// Decodifica a resposta com o cookie no inicio, a resposta invalida retorna o erro somente no comando do cookie
+func (c *Client) decodificarCookie(mensagem []byte) (string, *ResponseRtp, error) {
+	raw, _, _ := bytes.Cut(mensagem, []byte(" "))
+	cookie := string(raw)
+	resposta, err := DecodeRespostaStrict(cookie, mensagem)
+	if err != nil {
+		// somente o comando do cookie falha, os outros pendentes continuam aguardando
+		return cookie, &ResponseRtp{Result: string(ResultError), ErrorReason: err.Error()}, nil
+	}
+	c.guardarRaw(resposta, mensagem)
+	return cookie, resposta, nil
+}
+
+var errMensagemIncompleta = errors.New("mensagem NG incompleta")
+
+// Tamanho da primeira mensagem NG do buffer, o cookie seguido do espaço e de um valor em bencode
+func tamanhoMensagem(buf []byte) (int, error) {
+	espaco := bytes.IndexByte(buf, ' ')
+	if espaco < 0 {
+		return 0, errMensagemIncompleta
+	}
+
+	return fimBencode(buf, espaco+1)
+}
+
+// Posição do fim do valor em bencode iniciado em pos
+func fimBencode(data []byte, pos int) (int, error) {
+	if pos >= len(data) {
+		return 0, errMensagemIncompleta
+	}
+
+	switch b := data[pos]; {
+	case b == 'i':
+		fim := bytes.IndexByte(data[pos:], 'e')
+		if fim < 0 {
+			return 0, errMensagemIncompleta
+		}
+		return pos + fim + 1, nil
+	case b == 'l' || b == 'd':
+		pos++
+		for {
+			if pos >= len(data) {
+				return 0, errMensagemIncompleta
+			}
+			if data[pos] == 'e' {
+				return pos + 1, nil
+			}
+
+			fim, err := fimBencode(data, pos)
+			if err != nil {
+				return 0, err
+			}
+			pos = fim
+		}
+	case b >= '0' && b <= '9':
+		separador := bytes.IndexByte(data[pos:], ':')
+		if separador < 0 {
+			return 0, errMensagemIncompleta
+		}
+
+		tamanho, err := strconv.Atoi(string(data[pos : pos+separador]))
+		if err != nil {
+			return 0, fmt.Errorf("tamanho da string bencode invalido: %w", err)
+		}
+
+		fim := pos + separador + 1 + tamanho
+		if fim > len(data) {
+			return 0, errMensagemIncompleta
+		}
+		return fim, nil
+	}
+	return 0, fmt.Errorf("valor bencode invalido %q na posição %d", data[pos], pos)
+}
+
+// Verifica se o rtpengine esta respondendo e retorna a latencia do ping, algumas versões respondem ok no lugar de pong
+func (c *Client) Ping() (time.Duration, error) {
+	inicio := time.Now()
+	resposta, err := c.executar(c.GetCookie(), &RequestRtp{Command: string(Ping)})
+	latencia := time.Since(inicio)
+	if err != nil {
+		return latencia, err
+	}
+
+	switch resposta.ResultType() {
+	case ResultPong, ResultOK:
+		return latencia, nil
+	case ResultError:
+		return latencia, errors.New(resposta.ErrorReason)
+	}
+	return latencia, fmt.Errorf("resposta %q inesperada ao ping", resposta.Result)
+}
+
+// Quantidade maxima de comandos do Batch aguardando resposta na conexão
+const batchPendentes = 16
+
+// Envia os comandos em sequencia na mesma conexão sem aguardar cada resposta, as respostas são associadas pelo cookie
+// e retornadas na ordem dos comandos, o comando com falha retorna uma resposta de erro com o motivo
+func (c *Client) Batch(cmds []*RequestRtp) []*ResponseRtp {
+	respostas := make([]*ResponseRtp, len(cmds))
+	if len(cmds) == 0 {
+		return respostas
+	}
+
+	conn, err := c.acquire()
+	if err != nil {
+		for i := range respostas {
+			respostas[i] = &ResponseRtp{Result: string(ResultError), ErrorReason: err.Error()}
+		}
+		return respostas
+	}
+
+	descartar := false
+	defer func() { c.release(conn, descartar) }()
+
+	type pendente struct {
+		indice int
+		inicio time.Time
+	}
+	falhar := func(cookie string, indice int, err error, inicio time.Time) {
+		respostas[indice] = &ResponseRtp{Result: string(ResultError), ErrorReason: err.Error()}
+		c.registrar(cookie, cmds[indice], nil, err, time.Since(inicio))
+	}
+
+	leitor := newLeitorRespostas(conn)
+	pendentes := make(map[string]pendente)
+	proximo := 0
+	for proximo < len(cmds) || len(pendentes) > 0 {
+		for proximo < len(cmds) && len(pendentes) < batchPendentes {
+			cookie := c.GetCookie()
+			if c.metrics != nil {
+				c.metrics.AddInFlight(1)
+			}
+
+			inicio := time.Now()
+			c.incrementarSdp(cmds[proximo])
+			if err := c.enviar(conn, cookie, cmds[proximo]); err != nil {
+				// a conexão sera descartada, os comandos ainda não enviados falham com o mesmo erro
+				descartar = true
+				falhar(cookie, proximo, err, inicio)
+				for proximo++; proximo < len(cmds); proximo++ {
+					if c.metrics != nil {
+						c.metrics.AddInFlight(1)
+					}
+					falhar(c.GetCookie(), proximo, err, time.Now())
+				}
+				break
+			}
+			pendentes[cookie] = pendente{indice: proximo, inicio: inicio}
+			proximo++
+		}
+
+		if len(pendentes) == 0 {
+			continue
+		}
+
+		cookie, resposta, err := c.receberCookie(leitor)
+		if err != nil {
+			// sem resposta dos pendentes, as respostas atrasadas serão ignoradas pelo cookie
+			descartar = true
+			for cookie, p := range pendentes {
+				falhar(cookie, p.indice, err, p.inicio)
+				delete(pendentes, cookie)
+			}
+			continue
+		}
+
+		p, ok := pendentes[cookie]
+		if !ok {
+			c.log.Debug().Msg("Resposta com cookie desconhecido ignorada " + cookie)
+			continue
+		}
+
+		delete(pendentes, cookie)
+		c.finalizar(cmds[p.indice], resposta)
+		respostas[p.indice] = resposta
+		c.registrar(cookie, cmds[p.indice], resposta, nil, time.Since(p.inicio))
+	}
+	return respostas
+}
+
+// Versão retornada pelos rtpengine que não informam a versão
+const VersionUnknown = "unknown"
+
+// Versão minima do rtpengine com o comando play media
+var versaoPlayMedia = [2]int{6, 2}
+
+// Consulta a versão do rtpengine no ping e no comando version, o resultado fica guardado no client
+// os rtpengine que não informam a versão retornam VersionUnknown
+func (c *Client) Version() (string, error) {
+	c.versaoMu.Lock()
+	defer c.versaoMu.Unlock()
+	if c.versao != "" {
+		return c.versao, nil
+	}
+
+	for _, comando := range []TipoComandos{Ping, Version} {
+		resposta, err := c.executar(c.GetCookie(), &RequestRtp{Command: string(comando)})
+		if err != nil {
+			return "", err
+		}
+
+		if versao, ok := resposta.Extra["version"].(string); ok && versao != "" {
+			c.versao = versao
+			return versao, nil
+		}
+	}
+
+	c.versao = VersionUnknown
+	return c.versao, nil
+}
+
+// Indica se a versão do rtpengine aceita o comando play media, a versão desconhecida retorna false
+func (c *Client) SupportsPlayMedia() bool {
+	versao, err := c.Version()
+	if err != nil {
+		return false
+	}
+
+	numero, ok := versaoNumero(versao)
+	return ok && (numero[0] > versaoPlayMedia[0] || numero[0] == versaoPlayMedia[0] && numero[1] >= versaoPlayMedia[1])
+}
+
+// Versão principal e secundaria da string de versão ex: mr11.5.1.3 retorna [11, 5]
+func versaoNumero(versao string) ([2]int, bool) {
+	partes := regexVersao.FindStringSubmatch(versao)
+	if partes == nil {
+		return [2]int{}, false
+	}
+
+	principal, _ := strconv.Atoi(partes[1])
+	secundaria, _ := strconv.Atoi(partes[2])
+	return [2]int{principal, secundaria}, true
+}
+
+var regexVersao = regexp.MustCompile(`(\d+)\.(\d+)`)
+
+// Envia o offer de um branch do forking paralelo, cada branch usa o mesmo call-id e from-tag com via-branch proprio
+func (c *Client) OfferBranch(callID, fromTag, branch, sdp string, options ...ParametrosOption) (*ResponseRtp, error) {
+	comando := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: callID, FromTag: fromTag, Sdp: sdp},
+		append([]ParametrosOption{comando.SetViaBranchTag(branch)}, options...)...)
 	if err != nil {
 		return nil, err
 	}
+	return c.executarResultado(request)
+}
+
+// Remove somente o branch informado, os outros branches e a chamada continuam ativos no rtpengine
+func (c *Client) DeleteBranch(callID, fromTag, branch string, options ...ParametrosOption) (*ResponseRtp, error) {
+	comando := &RequestRtp{}
+	request, err := SDPDelete(&ParamsOptString{CallId: callID, FromTag: fromTag},
+		append([]ParametrosOption{comando.SetViaBranchTag(branch)}, options...)...)
+	if err != nil {
+		return nil, err
+	}
+	return c.executarResultado(request)
+}
 
-	resposta := DecodeResposta(cookie, respostaRaw)
+// Inicia o encaminhamento da midia para cada destino no formato host:porta, o rtpengine aceita somente um
+// output-destination por comando então e enviado um start forwarding por destino, parando no primeiro erro
+func (c *Client) StartForwarding(parametros *ParamsOptString, destinos []string, options ...ParametrosOption) ([]*ResponseRtp, error) {
+	if len(destinos) == 0 {
+		return nil, fmt.Errorf("%s requer o destino do encaminhamento", StartForwarding)
+	}
+	for _, destino := range destinos {
+		if err := validarDestino("output-destination", destino); err != nil {
+			return nil, err
+		}
+	}
+
+	if parametros == nil {
+		return nil, fmt.Errorf("%s requer o call-id da sessão", StartForwarding)
+	}
+
+	respostas := make([]*ResponseRtp, 0, len(destinos))
+	for _, destino := range destinos {
+		// cada comando tem a sua copia dos parametros com o proprio destino
+		copia := *parametros
+		comando := &RequestRtp{}
+		request, err := SDPStartForwarding(&copia, append([]ParametrosOption{comando.SetOutputDestination(destino)}, options...)...)
+		if err != nil {
+			return respostas, err
+		}
+
+		resposta, err := c.executarResultado(request)
+		if err != nil {
+			return respostas, err
+		}
+		respostas = append(respostas, resposta)
+	}
+	return respostas, nil
+}
+
+// Executa o comando e retorna o erro informado pelo rtpengine
+func (c *Client) executarResultado(comando *RequestRtp) (*ResponseRtp, error) {
+	resposta, err := c.executar(c.GetCookie(), comando)
+	if err != nil {
+		return nil, err
+	}
+
+	if resposta.ResultType() == ResultError {
+		return resposta, errors.New(resposta.ErrorReason)
+	}
+	return resposta, nil
+}
+
+// Fluxo offer/answer de uma chamada no B2BUA, o call-id e o from-tag do offer são usados no answer
+type OfferAnswer struct {
+	client  *Client
+	CallId  string
+	FromTag string
+	ToTag   string
+	Offer   *ResponseRtp
+	Answer  *ResponseRtp
+}
+
+// Envia o offer com o SDP recebido no INVITE, o SDP reescrito para a proxima perna fica em Offer.Sdp
+func (c *Client) StartOfferAnswer(parametros *ParamsOptString, options ...ParametrosOption) (*OfferAnswer, error) {
+	request, err := SDPOffering(parametros, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	resposta, err := c.executarResultado(request)
+	if err != nil {
+		return nil, err
+	}
+	return &OfferAnswer{client: c, CallId: parametros.CallId, FromTag: parametros.FromTag, Offer: resposta}, nil
+}
+
+// Envia o answer com o SDP recebido no 200 OK e o to-tag da outra perna, o SDP reescrito para o originador fica em Answer.Sdp
+func (o *OfferAnswer) Complete(toTag, sdp string, options ...ParametrosOption) (*ResponseRtp, error) {
+	request, err := SDPAnswer(&ParamsOptString{CallId: o.CallId, FromTag: o.FromTag, ToTag: toTag, Sdp: sdp}, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	resposta, err := o.client.executarResultado(request)
+	if err != nil {
+		return nil, err
+	}
+	o.ToTag, o.Answer = toTag, resposta
 	return resposta, nil
 }
+
+// Consulta os detalhes da sessão no rtpengine pelo call-id
+func (c *Client) Query(callID string) (*QueryResponse, error) {
+	comando := &RequestRtp{
+		Command:         string(Query),
+		ParamsOptString: &ParamsOptString{CallId: callID},
+	}
+
+	resposta, err := c.executar(c.GetCookie(), comando)
+	if err != nil {
+		return nil, err
+	}
+
+	if resposta.ResultType() == ResultError {
+		return nil, errors.New(resposta.ErrorReason)
+	}
+	return &QueryResponse{ResponseRtp: resposta, CallId: callID}, nil
+}
+
+// Configura o gateway de fax T.38 no par offer/answer, a direção do gateway e escolhida no offer (re-INVITE)
+// com decode quando o lado do offer fala T.38 e force quando o lado do offer e audio, o answer não recebe a opção
+// offerT38 indica se quem gerou o offer e o lado T.38
+func (c *Client) T38Gateway(offer, answer *RequestRtp, offerT38 bool) error {
+	if offer == nil || offer.Command != string(Offer) {
+		return fmt.Errorf("T38Gateway requer um comando %s", Offer)
+	}
+
+	if answer == nil || answer.Command != string(Answer) {
+		return fmt.Errorf("T38Gateway requer um comando %s", Answer)
+	}
+
+	offerOption := T38Force
+	if offerT38 {
+		offerOption = T38Decode
+	}
+
+	if err := offer.SetT38([]T38{offerOption})(offer); err != nil {
+		return err
+	}
+
+	c.log.Debug().Msg("T.38 gateway offer: " + string(offerOption))
+	return nil
+}
@@ -1,10 +1,19 @@
 package rtpengine
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
+	bencode "github.com/anacrolix/torrent/bencode"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 )
 
@@ -35,6 +44,51 @@ func TestClientRequestNewClientWithClientHostname(t *testing.T) {
 	fmt.Println("Func:", t.Name(), "Valor:", rtp.ip, "PASS")
 }
 
+func TestClientRequestWithClientAddressFamily(t *testing.T) {
+	t.Run("IPv6", func(t *testing.T) {
+		rtp, err := NewClient(&Engine{},
+			WithClientAddressFamily(AddressFamilyIP6),
+			WithClientHostname("::1"),
+			WithClientProto("udp"))
+		require.Nil(t, err)
+		defer rtp.Close()
+		require.Equal(t, "::1", rtp.ip.String())
+	})
+
+	t.Run("Falha na resolução", func(t *testing.T) {
+		rtp, err := NewClient(&Engine{ip: net.ParseIP("10.0.0.0")},
+			WithClientHostname("rtpengine.invalid"),
+			WithClientProto("udp"))
+		require.NotNil(t, err)
+		require.Nil(t, rtp)
+		require.Contains(t, err.Error(), "rtpengine.invalid")
+	})
+
+	t.Run("Familia invalida", func(t *testing.T) {
+		_, err := NewClient(&Engine{}, WithClientAddressFamily("IP5"))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestWithClientConnectTimeout(t *testing.T) {
+	// o prazo expira antes do handshake terminar, simulando o backend que não completa a conexão
+	inicio := time.Now()
+	rtp, err := NewClient(&Engine{},
+		WithClientIP("10.255.255.1"),
+		WithClientPort(2222),
+		WithClientProto("tcp"),
+		WithClientConnectTimeout(time.Nanosecond))
+	require.Nil(t, err)
+	require.Nil(t, rtp.con)
+	require.Less(t, time.Since(inicio), time.Second)
+
+	_, err = rtp.dial()
+	require.True(t, timeoutErr(err))
+
+	_, err = NewClient(&Engine{}, WithClientConnectTimeout(0))
+	require.NotNil(t, err)
+}
+
 func TestClientRequestNewClienWithClientDns(t *testing.T) {
 	rtp, err := NewClient(
 		&Engine{
@@ -48,6 +102,148 @@ func TestClientRequestNewClienWithClientDns(t *testing.T) {
 	fmt.Println("Func:", t.Name(), "Valor:", rtp.url, "PASS")
 }
 
+// Servidor dns de teste que responde o registro A dos nomes informados e NXDOMAIN para os demais
+func newMockDNS(t testing.TB, registros map[string][]net.IP) string {
+	t.Helper()
+	srv, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.Nil(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := srv.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if n < 12 {
+				continue
+			}
+
+			// nome e tipo da pergunta logo apos o cabeçalho
+			fim, labels := 12, []string{}
+			for fim < n && buf[fim] != 0 {
+				labels = append(labels, string(buf[fim+1:fim+1+int(buf[fim])]))
+				fim += int(buf[fim]) + 1
+			}
+			pergunta := buf[12 : fim+5]
+			ips, ok := registros[strings.Join(labels, ".")]
+			tipo := buf[fim+2]
+
+			resposta := append([]byte{buf[0], buf[1], 0x81, 0x80, 0, 1, 0, 0, 0, 0, 0, 0}, pergunta...)
+			if !ok {
+				resposta[3] = 0x83
+			}
+			for _, ip := range ips {
+				switch {
+				case tipo == 1 && ip.To4() != nil:
+					resposta = append(resposta, 0xc0, 0x0c, 0, 1, 0, 1, 0, 0, 0, 60, 0, 4)
+					resposta = append(resposta, ip.To4()...)
+				case tipo == 28 && ip.To4() == nil:
+					resposta = append(resposta, 0xc0, 0x0c, 0, 28, 0, 1, 0, 0, 0, 60, 0, 16)
+					resposta = append(resposta, ip.To16()...)
+				default:
+					continue
+				}
+				resposta[7]++
+			}
+			srv.WriteToUDP(resposta, addr)
+		}
+	}()
+	return srv.LocalAddr().String()
+}
+
+func TestClientRequestWithClientResolver(t *testing.T) {
+	resolver := newMockDNS(t, map[string][]net.IP{"rtpengine.test": {net.ParseIP("127.0.0.10")}})
+
+	t.Run("Resolvido", func(t *testing.T) {
+		rtp, err := NewClient(&Engine{},
+			WithClientDns("rtpengine.test."),
+			WithClientResolver(resolver),
+			WithClientPort(2222),
+			WithClientProto("udp"))
+		require.Nil(t, err)
+		defer rtp.Close()
+		require.Equal(t, "127.0.0.10", rtp.url)
+		require.Equal(t, "127.0.0.10", rtp.ip.String())
+	})
+
+	t.Run("NXDOMAIN", func(t *testing.T) {
+		rtp, err := NewClient(&Engine{},
+			WithClientResolver(resolver),
+			WithClientDns("naoexiste.test."),
+			WithClientProto("udp"))
+		require.NotNil(t, err)
+		require.Nil(t, rtp)
+		require.Contains(t, err.Error(), "naoexiste.test")
+	})
+
+	t.Run("Resolver invalido", func(t *testing.T) {
+		_, err := NewClient(&Engine{}, WithClientResolver("127.0.0.1"))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestDualStack(t *testing.T) {
+	t.Run("TCP", func(t *testing.T) {
+		srv, err := net.Listen("tcp4", "127.0.0.1:0")
+		require.Nil(t, err)
+		defer srv.Close()
+		go func() {
+			for {
+				conn, err := srv.Accept()
+				if err != nil {
+					return
+				}
+				defer conn.Close()
+			}
+		}()
+
+		// o IPv6 não tem servidor na porta, somente o IPv4 conecta
+		resolver := newMockDNS(t, map[string][]net.IP{"rtpengine.test": {net.ParseIP("::1"), net.ParseIP("127.0.0.1")}})
+		rtp, err := NewClient(&Engine{},
+			WithClientHostname("rtpengine.test."),
+			WithClientResolver(resolver),
+			WithClientPort(srv.Addr().(*net.TCPAddr).Port),
+			WithClientProto("tcp"))
+		require.Nil(t, err)
+		defer rtp.Close()
+
+		require.NotNil(t, rtp.con)
+		require.Equal(t, "127.0.0.1", rtp.con.RemoteAddr().(*net.TCPAddr).IP.String())
+		require.NotNil(t, rtp.ipAlternativo)
+	})
+
+	t.Run("UDP", func(t *testing.T) {
+		srv := newMockEngine(t, func(cookie string, comando map[string]interface{}) []byte {
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		})
+		porta := srv.LocalAddr().(*net.UDPAddr).Port
+
+		// o IPv6 recebe os pacotes e não responde, o dial UDP dele tambem tem sucesso
+		morto, err := net.ListenUDP("udp6", &net.UDPAddr{IP: net.IPv6loopback, Port: porta})
+		if err != nil {
+			t.Skip("IPv6 indisponivel ", err.Error())
+		}
+		defer morto.Close()
+
+		resolver := newMockDNS(t, map[string][]net.IP{"rtpengine.test": {net.ParseIP("::1"), net.ParseIP("127.0.0.1")}})
+		rtp, err := NewClient(&Engine{},
+			WithClientHostname("rtpengine.test."),
+			WithClientResolver(resolver),
+			WithClientPort(porta),
+			WithClientProto("udp"),
+			WithClientConnectTimeout(500*time.Millisecond))
+		require.Nil(t, err)
+		defer rtp.Close()
+
+		require.NotNil(t, rtp.con)
+		require.Equal(t, "127.0.0.1", rtp.con.RemoteAddr().(*net.UDPAddr).IP.String())
+		_, err = rtp.Ping()
+		require.Nil(t, err)
+	})
+}
+
 func TestClientRequestClientOption(t *testing.T) {
 	t.Run("TestClientDNS", func(t *testing.T) {
 		c := &Engine{}
@@ -67,3 +263,1084 @@ func TestClientRequestClientOption(t *testing.T) {
 		b.con.Close()
 	})
 }
+
+// Servidor rtpengine de teste, cada comando recebido é respondido com o retorno do handler
+func newMockEngine(t testing.TB, handler func(cookie string, comando map[string]interface{}) []byte) *net.UDPConn {
+	t.Helper()
+	srv, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.Nil(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	go func() {
+		buf := make([]byte, 65536)
+		for {
+			n, addr, err := srv.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			cookie, data, _ := bytes.Cut(buf[:n], []byte(" "))
+			comando := make(map[string]interface{})
+			bencode.Unmarshal(data, &comando)
+			if resposta := handler(string(cookie), comando); resposta != nil {
+				srv.WriteToUDP(resposta, addr)
+			}
+		}
+	}()
+	return srv
+}
+
+// Monta a resposta em bencode com o cookie do comando
+func mockResposta(cookie string, resposta interface{}) []byte {
+	data, _ := bencode.Marshal(resposta)
+	return append([]byte(cookie+" "), data...)
+}
+
+// Cliente conectado ao servidor rtpengine de teste
+func newMockClient(t testing.TB, handler func(cookie string, comando map[string]interface{}) []byte, options ...ClientOption) *Client {
+	t.Helper()
+	srv := newMockEngine(t, handler)
+	options = append([]ClientOption{
+		WithClientIP("127.0.0.1"),
+		WithClientPort(srv.LocalAddr().(*net.UDPAddr).Port),
+		WithClientProto("udp"),
+	}, options...)
+	client, err := NewClient(&Engine{}, options...)
+	require.Nil(t, err)
+	t.Cleanup(func() { client.Close() })
+	return client
+}
+
+// Resposta gravada de um query com duas tags e dois streams por midia
+const queryDump = "d7:createdi1728899990e10:created_usi123456e11:last signali1728899995e6:result2:ok4:tagsd15:asdasdad7879000d7:createdi1728899991e16:in dialogue with19:asdasdasd494894AAAA6:mediasld5:flagsl11:initialized4:send4:recve5:indexi1e8:protocol7:RTP/AVP7:streamsld19:advertised endpointd7:address11:203.0.113.76:family4:IPv44:porti4000ee8:endpointd7:address11:203.0.113.76:family4:IPv44:porti4000ee5:flagsl3:RTP6:filled9:confirmede11:last packeti1728900000e10:local porti30002e5:statsd5:bytesi19200e6:errorsi0e7:packetsi120eeed19:advertised endpointd7:address11:203.0.113.76:family4:IPv44:porti4001ee8:endpointd7:address11:203.0.113.76:family4:IPv44:porti4001ee5:flagsl3:RTP6:filled9:confirmede11:last packeti1728900000e10:local porti30003e5:statsd5:bytesi19200e6:errorsi0e7:packetsi120eeee4:type5:audioee3:tag15:asdasdad7879000e19:asdasdasd494894AAAAd7:createdi1728899990e16:in dialogue with15:asdasdad78790006:mediasld5:flagsl11:initialized4:send4:recve5:indexi1e8:protocol7:RTP/AVP7:streamsld19:advertised endpointd7:address12:198.51.100.16:family4:IPv44:porti2000ee8:endpointd7:address12:198.51.100.16:family4:IPv44:porti2000ee5:flagsl3:RTP6:filled9:confirmede11:last packeti1728900000e10:local porti30000e5:statsd5:bytesi19200e6:errorsi0e7:packetsi120eeed19:advertised endpointd7:address12:198.51.100.16:family4:IPv44:porti2001ee8:endpointd7:address12:198.51.100.16:family4:IPv44:porti2001ee5:flagsl3:RTP6:filled9:confirmede11:last packeti1728900000e10:local porti30001e5:statsd5:bytesi19200e6:errorsi0e7:packetsi120eeee4:type5:audioee3:tag19:asdasdasd494894AAAAee6:totalsd4:RTCPd5:bytesi800e6:errorsi0e7:packetsi10ee3:RTPd5:bytesi38400e6:errorsi0e7:packetsi240eeee"
+
+func TestClientRequestQuery(t *testing.T) {
+	recebido := make(chan map[string]interface{}, 1)
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		recebido <- comando
+		return []byte(cookie + " " + queryDump)
+	})
+
+	response, err := client.Query("5464asdas00000000")
+	require.Nil(t, err)
+	comando := <-recebido
+	require.Equal(t, string(Query), comando["command"])
+	require.Equal(t, "5464asdas00000000", comando["call-id"])
+	require.Equal(t, "5464asdas00000000", response.CallId)
+	require.Len(t, response.Tags, 2)
+
+	tag := response.Tags["asdasdasd494894AAAA"]
+	require.Equal(t, "asdasdad7879000", tag.InDialogueWith)
+	require.Len(t, tag.Medias, 1)
+	require.Equal(t, "audio", tag.Medias[0].Type)
+	require.Equal(t, "RTP/AVP", tag.Medias[0].Protocol)
+	require.Equal(t, "198.51.100.1", tag.Medias[0].Streams[0].Endpoint.Address)
+	require.Equal(t, 2000, tag.Medias[0].Streams[0].Endpoint.Port)
+	require.Equal(t, 30000, tag.Medias[0].Streams[0].LocalPort)
+	require.Equal(t, 120, tag.Medias[0].Streams[0].Stats.Packets)
+}
+
+func TestClientRequestWithClientContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	}, WithClientContext(ctx))
+
+	require.NotNil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+
+	cancel()
+	require.Eventually(t, func() bool {
+		_, err := client.con.Write([]byte("ping"))
+		return errors.Is(err, net.ErrClosed)
+	}, time.Second, 10*time.Millisecond)
+
+	require.Nil(t, client.Close())
+
+	// o cancelamento concorre com a reconexão do Reset, com -race o acesso a conexão e verificado
+	ctx, cancel = context.WithCancel(context.Background())
+	client = newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	}, WithClientContext(ctx))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for client.Reset() == nil {
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	cancel()
+	wg.Wait()
+	require.ErrorIs(t, client.Reset(), net.ErrClosed)
+}
+
+// Conexão de teste que devolve uma resposta forjada
+type fakeConn struct {
+	net.Conn
+	read func(b []byte) (int, error)
+}
+
+func (f *fakeConn) Write(b []byte) (int, error)       { return len(b), nil }
+func (f *fakeConn) Read(b []byte) (int, error)        { return f.read(b) }
+func (f *fakeConn) SetReadDeadline(t time.Time) error { return nil }
+func (f *fakeConn) Close() error                      { return nil }
+
+func TestClientRequestComandoNG(t *testing.T) {
+	t.Run("SemPool", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		})
+		cookie := client.GetCookie()
+		require.Nil(t, client.ComandoNG(cookie, &RequestRtp{Command: string(Ping)}))
+		response, err := client.RespostaNG(cookie)
+		require.Nil(t, err)
+		require.Equal(t, "pong", response.Result)
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte { return nil }, WithClientPoolSize(2))
+		require.NotNil(t, client.ComandoNG(client.GetCookie(), &RequestRtp{Command: string(Ping)}))
+		_, err := client.RespostaNG(client.GetCookie())
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestCloseReconectado(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte { return nil }, WithClientPoolSize(1))
+	(<-client.pool).Close()
+	conn := &closeConn{}
+	client.con = conn
+
+	// a conexão padrão quebrada e descartada pelo pool antes do Close
+	client.release(conn, true)
+	require.Nil(t, client.Close())
+	require.Equal(t, int32(1), conn.fechado.Load())
+}
+
+type closeConn struct {
+	fakeConn
+	fechado atomic.Int32
+}
+
+func (c *closeConn) Close() error {
+	if c.fechado.Add(1) > 1 {
+		return net.ErrClosed
+	}
+	return nil
+}
+
+func TestClientRequestPanicRecovery(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte { return nil })
+	client.con = &fakeConn{read: func(b []byte) (int, error) {
+		// tamanho maior que o buffer lido
+		return len(b) + 1, nil
+	}}
+
+	_, err := client.RespostaNG("cookie")
+	require.NotNil(t, err)
+
+	var response *ResponseRtp
+	require.NotPanics(t, func() {
+		response = client.NewComando(&RequestRtp{Command: string(Ping)})
+	})
+	require.Nil(t, response)
+
+	client.observer = func(string, *RequestRtp, *ResponseRtp, time.Duration, error) { panic("observer") }
+	require.NotPanics(t, func() {
+		response = client.NewComando(&RequestRtp{Command: string(Ping)})
+	})
+	require.NotNil(t, response)
+	require.Equal(t, "error", response.Result)
+	require.NotEmpty(t, response.ErrorReason)
+}
+
+func TestClientRequestComandoLogger(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	})
+	buf := &bytes.Buffer{}
+	client.log = zerolog.New(buf).Level(zerolog.DebugLevel)
+
+	response := client.NewComando(&RequestRtp{
+		Command:         string(Delete),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas00000000", FromTag: "asdasdasd494894"},
+	})
+	require.NotNil(t, response)
+	require.Contains(t, buf.String(), `"call-id":"5464asdas00000000"`)
+	require.Contains(t, buf.String(), `"command":"delete"`)
+}
+
+func TestClientRequestSetLogLevel(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	})
+	buf := &bytes.Buffer{}
+	client.log = zerolog.New(buf).Level(zerolog.WarnLevel)
+
+	require.Nil(t, client.SetLogLevel(int8(zerolog.DebugLevel)))
+	client.log.Debug().Msg("mensagem debug")
+	require.Contains(t, buf.String(), "mensagem debug")
+
+	buf.Reset()
+	require.Nil(t, client.SetLogLevel(int8(zerolog.ErrorLevel)))
+	client.log.Debug().Msg("mensagem debug")
+	require.Empty(t, buf.String())
+
+	require.NotNil(t, client.SetLogLevel(8))
+	require.NotNil(t, client.SetLogLevel(-2))
+}
+
+func TestClientRequestPing(t *testing.T) {
+	t.Run("Pong", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		})
+		latencia, err := client.Ping()
+		require.Nil(t, err)
+		require.Greater(t, latencia, time.Duration(0))
+	})
+
+	t.Run("Ok", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+		})
+		_, err := client.Ping()
+		require.Nil(t, err)
+	})
+
+	t.Run("Erro", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			return mockResposta(cookie, map[string]interface{}{"result": "error", "error-reason": "Unrecognized command"})
+		})
+		_, err := client.Ping()
+		require.NotNil(t, err)
+		require.Equal(t, "Unrecognized command", err.Error())
+	})
+
+	t.Run("EngineFora", func(t *testing.T) {
+		client, err := NewClient(&Engine{}, WithClientIP("127.0.0.1"), WithClientPort(portaRecusada(t)), WithClientProto("tcp"))
+		require.Nil(t, err)
+		defer client.Close()
+
+		require.NotPanics(t, func() { _, err = client.Ping() })
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestWithClientKeepalive(t *testing.T) {
+	t.Run("Ping ocioso", func(t *testing.T) {
+		pings := make(chan string, 10)
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			if comando["command"] == string(Ping) {
+				select {
+				case pings <- cookie:
+				default:
+				}
+			}
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		}, WithClientKeepalive(20*time.Millisecond))
+
+		select {
+		case <-pings:
+		case <-time.After(time.Second):
+			t.Fatal("keepalive não enviou o ping")
+		}
+
+		response := client.NewComando(&RequestRtp{Command: string(Query), ParamsOptString: &ParamsOptString{CallId: "5464asdas"}})
+		require.NotNil(t, response)
+		require.Equal(t, "pong", response.Result)
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		pings := make(chan string, 10)
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			select {
+			case pings <- cookie:
+			default:
+			}
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		}, WithClientPoolSize(2), WithClientKeepalive(20*time.Millisecond))
+
+		for i := 0; i < 2; i++ {
+			select {
+			case <-pings:
+			case <-time.After(time.Second):
+				t.Fatal("keepalive não enviou o ping")
+			}
+		}
+		_, err := client.Ping()
+		require.Nil(t, err)
+	})
+
+	t.Run("Intervalo invalido", func(t *testing.T) {
+		_, err := NewClient(&Engine{}, WithClientKeepalive(0))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestWithClientKeepRaw(t *testing.T) {
+	escritos := make(chan []byte, 1)
+	handler := func(cookie string, comando map[string]interface{}) []byte {
+		resposta := mockResposta(cookie, map[string]interface{}{"result": "pong", "new-field": "valor"})
+		escritos <- resposta
+		return resposta
+	}
+
+	client := newMockClient(t, handler, WithClientKeepRaw())
+	response := client.NewComando(&RequestRtp{Command: string(Ping)})
+	require.NotNil(t, response)
+	require.Equal(t, <-escritos, response.Raw())
+
+	client = newMockClient(t, handler)
+	response = client.NewComando(&RequestRtp{Command: string(Ping)})
+	require.NotNil(t, response)
+	<-escritos
+	require.Nil(t, response.Raw())
+}
+
+func TestClientRequestPool(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong", "warning": cookie})
+	}, WithClientPoolSize(3))
+	require.Len(t, client.pool, 3)
+
+	t.Run("Concorrencia", func(t *testing.T) {
+		var wg sync.WaitGroup
+		erros := make(chan string, 30)
+		for i := 0; i < 30; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				response := client.NewComando(&RequestRtp{Command: string(Ping)})
+				if response == nil || response.Result != "pong" {
+					erros <- "resposta invalida"
+				}
+			}()
+		}
+		wg.Wait()
+		close(erros)
+		require.Empty(t, erros)
+		require.Len(t, client.pool, 3)
+	})
+
+	t.Run("Conexão fechada", func(t *testing.T) {
+		conexoes := []net.Conn{<-client.pool, <-client.pool, <-client.pool}
+		conexoes[0].Close()
+		for _, conn := range conexoes {
+			client.pool <- conn
+		}
+
+		for i := 0; i < 6; i++ {
+			response := client.NewComando(&RequestRtp{Command: string(Ping)})
+			require.NotNil(t, response)
+			require.Equal(t, "pong", response.Result)
+		}
+		require.Len(t, client.pool, 3)
+	})
+}
+
+func BenchmarkClientConexaoPorComando(b *testing.B) {
+	srv := newMockEngine(b, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	})
+	port := srv.LocalAddr().(*net.UDPAddr).Port
+
+	for i := 0; i < b.N; i++ {
+		client, err := NewClient(&Engine{}, WithClientIP("127.0.0.1"), WithClientPort(port), WithClientProto("udp"))
+		if err != nil {
+			b.Fatal(err)
+		}
+		client.NewComando(&RequestRtp{Command: string(Ping)})
+		client.Close()
+	}
+}
+
+func BenchmarkClientPool(b *testing.B) {
+	client := newMockClient(b, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	}, WithClientPoolSize(4))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		client.NewComando(&RequestRtp{Command: string(Ping)})
+	}
+}
+
+func TestClientRequestWithClientObserver(t *testing.T) {
+	type chamada struct {
+		cookie   string
+		comando  *RequestRtp
+		resposta *ResponseRtp
+		latencia time.Duration
+		err      error
+	}
+	chamadas := make([]chamada, 0)
+
+	var semResposta atomic.Bool
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		if semResposta.Load() {
+			return nil
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	}, WithClientObserver(func(cookie string, comando *RequestRtp, resposta *ResponseRtp, latency time.Duration, err error) {
+		chamadas = append(chamadas, chamada{cookie, comando, resposta, latency, err})
+	}))
+	client.timeout = 100 * time.Millisecond
+
+	ping := &RequestRtp{Command: string(Ping)}
+	require.NotNil(t, client.NewComando(ping))
+	require.Len(t, chamadas, 1)
+	require.NotEmpty(t, chamadas[0].cookie)
+	require.Equal(t, ping, chamadas[0].comando)
+	require.Equal(t, "pong", chamadas[0].resposta.Result)
+	require.Greater(t, chamadas[0].latencia, time.Duration(0))
+	require.Nil(t, chamadas[0].err)
+
+	semResposta.Store(true)
+	require.Nil(t, client.NewComando(ping))
+	require.Len(t, chamadas, 2)
+	require.Nil(t, chamadas[1].resposta)
+	require.NotNil(t, chamadas[1].err)
+}
+
+func TestClientRequestBatch(t *testing.T) {
+	var recebidos int32
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		atomic.AddInt32(&recebidos, 1)
+		return mockResposta(cookie, map[string]interface{}{"result": "pong", "warning": cookie})
+	})
+
+	cmds := make([]*RequestRtp, 10)
+	for i := range cmds {
+		cmds[i] = &RequestRtp{Command: string(Ping)}
+	}
+
+	respostas := client.Batch(cmds)
+	require.Len(t, respostas, 10)
+	cookies := make(map[string]bool)
+	for i, resposta := range respostas {
+		require.NotNil(t, resposta)
+		require.Equal(t, "pong", resposta.Result)
+		require.Same(t, cmds[i], resposta.comando)
+		require.False(t, cookies[resposta.Warning])
+		cookies[resposta.Warning] = true
+	}
+	require.Equal(t, int32(10), atomic.LoadInt32(&recebidos))
+	require.Empty(t, client.Batch(nil))
+}
+
+func TestClientRequestBatchForaDeOrdem(t *testing.T) {
+	srv, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.ParseIP("127.0.0.1")})
+	require.Nil(t, err)
+	t.Cleanup(func() { srv.Close() })
+
+	// responde os comandos em ordem inversa e ignora o delete da chamada inexistente
+	go func() {
+		buf := make([]byte, 65536)
+		comandos := make([][]byte, 0)
+		var addr *net.UDPAddr
+		for len(comandos) < 3 {
+			n, a, err := srv.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			addr = a
+			comandos = append(comandos, append([]byte{}, buf[:n]...))
+		}
+		for i := len(comandos) - 1; i >= 0; i-- {
+			cookie, data, _ := bytes.Cut(comandos[i], []byte(" "))
+			comando := make(map[string]interface{})
+			bencode.Unmarshal(data, &comando)
+			if comando["call-id"] == "perdido" {
+				continue
+			}
+			srv.WriteToUDP(mockResposta(string(cookie), map[string]interface{}{"result": "ok", "warning": comando["call-id"]}), addr)
+		}
+	}()
+
+	client, err := NewClient(&Engine{}, WithClientIP("127.0.0.1"), WithClientPort(srv.LocalAddr().(*net.UDPAddr).Port), WithClientProto("udp"))
+	require.Nil(t, err)
+	defer client.Close()
+	client.timeout = 200 * time.Millisecond
+
+	respostas := client.Batch([]*RequestRtp{
+		{Command: string(Delete), ParamsOptString: &ParamsOptString{CallId: "primeiro"}},
+		{Command: string(Delete), ParamsOptString: &ParamsOptString{CallId: "perdido"}},
+		{Command: string(Delete), ParamsOptString: &ParamsOptString{CallId: "terceiro"}},
+	})
+	require.Equal(t, "primeiro", respostas[0].Warning)
+	require.Equal(t, "error", respostas[1].Result)
+	require.NotEmpty(t, respostas[1].ErrorReason)
+	require.Equal(t, "terceiro", respostas[2].Warning)
+}
+
+func TestClientRequestBatchTCP(t *testing.T) {
+	srv, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	defer srv.Close()
+
+	// responde em ordem inversa, as duas primeiras respostas em uma escrita e a ultima dividida em duas
+	go func() {
+		conn, err := srv.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var recebido []byte
+		comandos := make([][]byte, 0)
+		buf := make([]byte, 65536)
+		for len(comandos) < 3 {
+			n, err := conn.Read(buf)
+			if err != nil {
+				return
+			}
+			recebido = append(recebido, buf[:n]...)
+			for {
+				fim, err := tamanhoMensagem(recebido)
+				if err != nil {
+					break
+				}
+				comandos = append(comandos, recebido[:fim])
+				recebido = recebido[fim:]
+			}
+		}
+
+		respostas := make([][]byte, 0)
+		for i := len(comandos) - 1; i >= 0; i-- {
+			cookie, data, _ := bytes.Cut(comandos[i], []byte(" "))
+			comando := make(map[string]interface{})
+			bencode.Unmarshal(data, &comando)
+			respostas = append(respostas, mockResposta(string(cookie), map[string]interface{}{"result": "ok", "warning": comando["call-id"]}))
+		}
+		conn.Write(append(append([]byte{}, respostas[0]...), respostas[1]...))
+		time.Sleep(20 * time.Millisecond)
+		conn.Write(respostas[2][:10])
+		time.Sleep(20 * time.Millisecond)
+		conn.Write(respostas[2][10:])
+		time.Sleep(200 * time.Millisecond)
+	}()
+
+	client, err := NewClient(&Engine{}, WithClientIP("127.0.0.1"), WithClientPort(srv.Addr().(*net.TCPAddr).Port), WithClientProto("tcp"),
+		WithClientTrackSessions())
+	require.Nil(t, err)
+	defer client.Close()
+	client.timeout = time.Second
+	client.sessoes["antigo"] = struct{}{}
+
+	respostas := client.Batch([]*RequestRtp{
+		{Command: string(Offer), ParamsOptString: &ParamsOptString{CallId: "primeiro", Sdp: sdpTeste}},
+		{Command: string(Offer), ParamsOptString: &ParamsOptString{CallId: "segundo", Sdp: sdpTeste}},
+		{Command: string(Delete), ParamsOptString: &ParamsOptString{CallId: "antigo"}},
+	})
+	require.Equal(t, "primeiro", respostas[0].Warning)
+	require.Equal(t, "segundo", respostas[1].Warning)
+	require.Equal(t, "antigo", respostas[2].Warning)
+	for _, resposta := range respostas {
+		require.Equal(t, "ok", resposta.Result)
+	}
+	require.ElementsMatch(t, []string{"primeiro", "segundo"}, client.Sessions())
+}
+
+// Transporte de teste que falha no envio a partir do comando informado
+type falhaTransport struct {
+	*fakeTransport
+	limite int
+}
+
+func (f *falhaTransport) Send(menssagem []byte) error {
+	if f.limite--; f.limite < 0 {
+		return net.ErrClosed
+	}
+	return f.fakeTransport.Send(menssagem)
+}
+
+func TestClientRequestBatchErroEnvio(t *testing.T) {
+	transport := &falhaTransport{limite: 1, fakeTransport: newFakeTransport(func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	})}
+	client, err := NewClient(&Engine{}, WithClientTransport(transport))
+	require.Nil(t, err)
+	defer client.Close()
+
+	respostas := client.Batch([]*RequestRtp{{Command: string(Ping)}, {Command: string(Ping)}, {Command: string(Ping)}})
+	require.Equal(t, "pong", respostas[0].Result)
+	require.Equal(t, "error", respostas[1].Result)
+	require.Equal(t, "error", respostas[2].Result)
+	// o terceiro comando não e enviado na conexão descartada
+	require.Equal(t, -1, transport.limite)
+	require.Len(t, transport.enviados, 1)
+}
+
+func TestClientRequestOfferBranch(t *testing.T) {
+	var mu sync.Mutex
+	branches := make(map[string]bool)
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+
+		branch, _ := comando["via-branch"].(string)
+		switch comando["command"] {
+		case string(Offer):
+			branches[branch] = true
+			return mockResposta(cookie, map[string]interface{}{"result": "ok", "sdp": comando["sdp"]})
+		case string(Delete):
+			if branch == "" {
+				branches = make(map[string]bool)
+				return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+			}
+			if !branches[branch] {
+				return mockResposta(cookie, map[string]interface{}{"result": "error", "error-reason": "Unknown call-ID"})
+			}
+			delete(branches, branch)
+			return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "error", "error-reason": "Unrecognized command"})
+	})
+
+	for _, branch := range []string{"z9hG4bK776asdhds.1", "z9hG4bK776asdhds.2"} {
+		response, err := client.OfferBranch("5464asdas", "asdasdasd494894", branch, sdpTeste)
+		require.Nil(t, err)
+		require.Equal(t, "ok", response.Result)
+		require.Equal(t, branch, response.comando.ViaBranch)
+	}
+
+	_, err := client.DeleteBranch("5464asdas", "asdasdasd494894", "z9hG4bK776asdhds.1")
+	require.Nil(t, err)
+	mu.Lock()
+	require.Equal(t, map[string]bool{"z9hG4bK776asdhds.2": true}, branches)
+	mu.Unlock()
+
+	_, err = client.DeleteBranch("5464asdas", "asdasdasd494894", "z9hG4bK776asdhds.1")
+	require.NotNil(t, err)
+
+	_, err = client.DeleteBranch("5464asdas", "asdasdasd494894", "")
+	require.NotNil(t, err)
+}
+
+func TestClientRequestStartForwarding(t *testing.T) {
+	recebido := make(chan map[string]interface{}, 2)
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		recebido <- comando
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	})
+
+	parametros := &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"}
+	respostas, err := client.StartForwarding(parametros, []string{"198.51.100.20:5000", "[2001:db8::20]:5002"})
+	require.Nil(t, err)
+	require.Len(t, respostas, 2)
+	require.Empty(t, parametros.OutputDestination)
+
+	for _, destino := range []string{"198.51.100.20:5000", "[2001:db8::20]:5002"} {
+		comando := <-recebido
+		require.Equal(t, string(StartForwarding), comando["command"])
+		require.Equal(t, "asdasdasd494894", comando["from-tag"])
+		require.Equal(t, destino, comando["output-destination"])
+	}
+
+	_, err = client.StartForwarding(parametros, []string{"198.51.100.20:5000", "198.51.100.20"})
+	require.NotNil(t, err)
+	_, err = client.StartForwarding(parametros, []string{"198.51.100.20:0"})
+	require.NotNil(t, err)
+	_, err = client.StartForwarding(parametros, nil)
+	require.NotNil(t, err)
+	require.Len(t, recebido, 0)
+}
+
+func TestClientRequestOfferAnswer(t *testing.T) {
+	recebidos := make(chan map[string]interface{}, 2)
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		recebidos <- comando
+		endereco := "203.0.113.5"
+		if comando["command"] == string(Answer) {
+			endereco = "203.0.113.9"
+		}
+		sdp := strings.ReplaceAll(comando["sdp"].(string), "198.51.100.1", endereco)
+		return mockResposta(cookie, map[string]interface{}{"result": "ok", "sdp": sdp})
+	})
+
+	fluxo, err := client.StartOfferAnswer(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste})
+	require.Nil(t, err)
+	require.Contains(t, fluxo.Offer.Sdp, "c=IN IP4 203.0.113.5")
+	offer := <-recebidos
+	require.Equal(t, string(Offer), offer["command"])
+
+	response, err := fluxo.Complete("asdasdad7879000", sdpTeste)
+	require.Nil(t, err)
+	require.Contains(t, response.Sdp, "c=IN IP4 203.0.113.9")
+	require.Same(t, response, fluxo.Answer)
+
+	answer := <-recebidos
+	require.Equal(t, string(Answer), answer["command"])
+	require.Equal(t, "5464asdas", answer["call-id"])
+	require.Equal(t, "asdasdasd494894", answer["from-tag"])
+	require.Equal(t, "asdasdad7879000", answer["to-tag"])
+}
+
+func TestClientRequestShutdown(t *testing.T) {
+	var mu sync.Mutex
+	sessoes := make(map[string]bool)
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+
+		callId, _ := comando["call-id"].(string)
+		switch comando["command"] {
+		case string(Offer):
+			sessoes[callId] = true
+		case string(Delete):
+			delete(sessoes, callId)
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	}, WithClientTrackSessions())
+
+	for _, callId := range []string{"5464asdas.1", "5464asdas.2"} {
+		request, err := SDPOffering(&ParamsOptString{CallId: callId, FromTag: "asdasdasd494894", Sdp: sdpTeste})
+		require.Nil(t, err)
+		require.NotNil(t, client.NewComando(request))
+	}
+	require.Equal(t, []string{"5464asdas.1", "5464asdas.2"}, client.Sessions())
+
+	falhas, err := client.Shutdown(context.Background())
+	require.Nil(t, err)
+	require.Empty(t, falhas)
+	require.Empty(t, client.Sessions())
+
+	mu.Lock()
+	require.Empty(t, sessoes)
+	mu.Unlock()
+
+	// rtpengine sem resposta, o shutdown termina no prazo do contexto sem enviar os deletes restantes
+	var deletes atomic.Int32
+	mudo := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		if comando["command"] == string(Offer) {
+			return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+		}
+		return nil
+	}, WithClientTrackSessions(), WithClientObserver(func(cookie string, comando *RequestRtp, resposta *ResponseRtp, latency time.Duration, err error) {
+		if comando.Command == string(Delete) {
+			deletes.Add(1)
+		}
+	}))
+
+	for _, callId := range []string{"5464asdas.3", "5464asdas.4"} {
+		request, err := SDPOffering(&ParamsOptString{CallId: callId, FromTag: "asdasdasd494894", Sdp: sdpTeste})
+		require.Nil(t, err)
+		require.NotNil(t, mudo.NewComando(request))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	inicio := time.Now()
+	falhas, err = mudo.Shutdown(ctx)
+	enviados := deletes.Load()
+	require.Less(t, time.Since(inicio), time.Second)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Equal(t, []string{"5464asdas.3", "5464asdas.4"}, falhas)
+
+	time.Sleep(150 * time.Millisecond)
+	require.Equal(t, enviados, deletes.Load())
+}
+
+func TestClientRequestWithClientRetryPolicy(t *testing.T) {
+	var tentativas atomic.Int32
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		if tentativas.Add(1) == 1 {
+			return mockResposta(cookie, map[string]interface{}{"result": "error", "error-reason": "load limit"})
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	}, WithClientRetryPolicy(func(resp *ResponseRtp, attempt int) (bool, time.Duration) {
+		return resp.ResultType() == ResultError && resp.ErrorReason == "load limit" && attempt < 3, 10 * time.Millisecond
+	}))
+
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste})
+	require.Nil(t, err)
+	response := client.NewComando(request)
+	require.NotNil(t, response)
+	require.Equal(t, "ok", response.Result)
+	require.Equal(t, int32(2), tentativas.Load())
+
+	// delete não e idempotente e não e repetido
+	tentativas.Store(0)
+	request, err = SDPDelete(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+	require.Nil(t, err)
+	response = client.NewComando(request)
+	require.NotNil(t, response)
+	require.Equal(t, "load limit", response.ErrorReason)
+	require.Equal(t, int32(1), tentativas.Load())
+
+	_, err = NewClient(&Engine{}, WithClientRetryPolicy(nil))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestWithCommandTimeout(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		if comando["command"] == string(PlayMedia) {
+			time.Sleep(300 * time.Millisecond)
+			return mockResposta(cookie, map[string]interface{}{"result": "ok", "duration": 300})
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	})
+	client.timeout = 100 * time.Millisecond
+
+	opt := &RequestRtp{}
+	ping := &RequestRtp{Command: string(Ping)}
+	require.Nil(t, opt.WithCommandTimeout(50*time.Millisecond)(ping))
+	response := client.NewComando(ping)
+	require.NotNil(t, response)
+	require.Equal(t, ResultPong, response.ResultType())
+
+	play := &RequestRtp{
+		Command:         string(PlayMedia),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", File: "/var/media/anuncio.wav"},
+	}
+	require.Nil(t, opt.WithCommandTimeout(time.Second)(play))
+	response = client.NewComando(play)
+	require.NotNil(t, response)
+	require.Equal(t, ResultOK, response.ResultType())
+
+	// o proximo comando volta a usar o timeout do client
+	require.Equal(t, 100*time.Millisecond, client.prazo(&RequestRtp{Command: string(Ping)}))
+	require.NotNil(t, opt.WithCommandTimeout(0)(ping))
+}
+
+func TestClientRequestWithClientAutoIncrementSDP(t *testing.T) {
+	replaces := make(chan interface{}, 4)
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		if comando["command"] == string(Offer) {
+			replaces <- comando["replace"]
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	}, WithClientAutoIncrementSDP())
+
+	offer := func() {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste})
+		require.Nil(t, err)
+		require.NotNil(t, client.NewComando(request))
+	}
+
+	offer()
+	require.Nil(t, <-replaces)
+
+	offer()
+	require.Equal(t, []interface{}{string(ForceIncrementSdpVersion)}, <-replaces)
+
+	// depois do delete o call-id volta a ser um offer inicial
+	request, err := SDPDelete(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+	require.Nil(t, err)
+	require.NotNil(t, client.NewComando(request))
+
+	offer()
+	require.Nil(t, <-replaces)
+}
+
+func TestClientRequestVersion(t *testing.T) {
+	t.Run("Versão no ping", func(t *testing.T) {
+		var pings atomic.Int32
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			pings.Add(1)
+			return []byte(cookie + " d6:result4:pong7:version20:mr11.5.1.3-1~bpo12+1e")
+		})
+
+		versao, err := client.Version()
+		require.Nil(t, err)
+		require.Equal(t, "mr11.5.1.3-1~bpo12+1", versao)
+		require.True(t, client.SupportsPlayMedia())
+		require.Equal(t, int32(1), pings.Load())
+	})
+
+	t.Run("Versão antiga", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			if comando["command"] == string(Version) {
+				return mockResposta(cookie, map[string]interface{}{"result": "ok", "version": "5.5.10.2"})
+			}
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		})
+
+		versao, err := client.Version()
+		require.Nil(t, err)
+		require.Equal(t, "5.5.10.2", versao)
+		require.False(t, client.SupportsPlayMedia())
+	})
+
+	t.Run("Sem versão", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			if comando["command"] == string(Version) {
+				return mockResposta(cookie, map[string]interface{}{"result": "error", "error-reason": "Unrecognized command"})
+			}
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		})
+
+		versao, err := client.Version()
+		require.Nil(t, err)
+		require.Equal(t, VersionUnknown, versao)
+		require.False(t, client.SupportsPlayMedia())
+	})
+}
+
+func TestClientRequestSemAtraso(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	})
+
+	// a leitura usa o prazo da conexão, sem espera fixa antes da resposta
+	inicio := time.Now()
+	require.NotNil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+	require.Less(t, time.Since(inicio), 100*time.Millisecond)
+}
+
+func TestClientRequestDeleteByPrefix(t *testing.T) {
+	var mu sync.Mutex
+	removidos := make([]string, 0)
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch comando["command"] {
+		case string(List):
+			return mockResposta(cookie, map[string]interface{}{"result": "ok", "calls": []string{"tenantA-1", "tenantB-1", "tenantA-2", "tenantA-3"}})
+		case string(Delete):
+			if comando["call-id"] == "tenantA-3" {
+				return mockResposta(cookie, map[string]interface{}{"result": "error", "error-reason": "Internal error"})
+			}
+			removidos = append(removidos, comando["call-id"].(string))
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	})
+
+	resultado, err := client.DeleteByPrefix(context.Background(), "tenantA-")
+	require.Nil(t, err)
+	require.Equal(t, []string{"tenantA-1", "tenantA-2"}, resultado.Deleted)
+	require.Len(t, resultado.Failed, 1)
+	require.EqualError(t, resultado.Failed["tenantA-3"], "Internal error")
+
+	mu.Lock()
+	require.Equal(t, []string{"tenantA-1", "tenantA-2"}, removidos)
+	mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.DeleteMatching(ctx, func(callId string) bool { return true })
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestClientRequestWithClientLegacyFlags(t *testing.T) {
+	transport := newFakeTransport(func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	})
+	client, err := NewClient(&Engine{}, WithClientTransport(transport), WithClientLegacyFlags())
+	require.Nil(t, err)
+	defer client.Close()
+
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.TrustAddress(),
+		opt.Symmetric(),
+		opt.SetReplace([]ParamReplace{Origin, SessionName}))
+	require.Nil(t, err)
+	require.NotNil(t, client.NewComando(request))
+
+	data := string(transport.enviados[0])
+	require.Contains(t, data, "5:flags23:trust-address,symmetric")
+	require.Contains(t, data, "7:replace19:origin,session-name")
+
+	// sem o modo legado as flags continuam como lista
+	data = string(mustEncode(t, request))
+	require.Contains(t, data, "5:flagsl13:trust-address9:symmetrice")
+}
+
+// Codifica o comando no formato padrão
+func mustEncode(t testing.TB, request *RequestRtp) []byte {
+	t.Helper()
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	return data
+}
+
+func TestClientRequestReset(t *testing.T) {
+	handler := func(atrasar *atomic.Bool) func(cookie string, comando map[string]interface{}) []byte {
+		return func(cookie string, comando map[string]interface{}) []byte {
+			if atrasar.CompareAndSwap(true, false) {
+				time.Sleep(150 * time.Millisecond)
+				return mockResposta(cookie, map[string]interface{}{"result": "ok", "warning": "atrasada"})
+			}
+			return mockResposta(cookie, map[string]interface{}{"result": "pong", "warning": cookie})
+		}
+	}
+
+	t.Run("Reset", func(t *testing.T) {
+		atrasar := &atomic.Bool{}
+		atrasar.Store(true)
+		client := newMockClient(t, handler(atrasar))
+		client.timeout = 50 * time.Millisecond
+
+		require.Nil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+		require.Nil(t, client.Reset())
+
+		client.timeout = time.Second
+		cookie := client.GetCookie()
+		response, err := client.executar(cookie, &RequestRtp{Command: string(Ping)})
+		require.Nil(t, err)
+		require.Equal(t, "pong", response.Result)
+		require.Equal(t, cookie, response.Warning)
+	})
+
+	t.Run("WithClientResetOnTimeout", func(t *testing.T) {
+		atrasar := &atomic.Bool{}
+		atrasar.Store(true)
+		client := newMockClient(t, handler(atrasar), WithClientResetOnTimeout())
+		client.timeout = 50 * time.Millisecond
+
+		require.Nil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+		time.Sleep(200 * time.Millisecond)
+
+		client.timeout = time.Second
+		response := client.NewComando(&RequestRtp{Command: string(Ping)})
+		require.NotNil(t, response)
+		require.Equal(t, "pong", response.Result)
+	})
+
+	t.Run("Pool", func(t *testing.T) {
+		client := newMockClient(t, handler(&atomic.Bool{}), WithClientPoolSize(2))
+		require.Nil(t, client.Reset())
+		_, err := client.Ping()
+		require.Nil(t, err)
+	})
+}
+
+func TestClientRequestSemConexao(t *testing.T) {
+	t.Run("Reconecta", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		})
+		client.con.Close()
+		client.con = nil
+
+		response := client.NewComando(&RequestRtp{Command: string(Ping)})
+		require.NotNil(t, response)
+		require.Equal(t, "pong", response.Result)
+		require.NotNil(t, client.con)
+		require.Equal(t, int64(1), client.Stats().Reconnects)
+	})
+
+	t.Run("EngineFora", func(t *testing.T) {
+		client, err := NewClient(&Engine{}, WithClientIP("127.0.0.1"), WithClientPort(portaRecusada(t)), WithClientProto("tcp"))
+		require.Nil(t, err)
+		defer client.Close()
+
+		_, err = client.executar(client.GetCookie(), &RequestRtp{Command: string(Ping)})
+		require.NotNil(t, err)
+		require.True(t, client.mu.TryLock())
+		client.mu.Unlock()
+	})
+}
+
+// Porta TCP local sem listener, a conexão e recusada
+func portaRecusada(t testing.TB) int {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.Nil(t, err)
+	porta := ln.Addr().(*net.TCPAddr).Port
+	ln.Close()
+	return porta
+}
@@ -0,0 +1,93 @@
+package rtpengine
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Coletor de metricas dos comandos enviados ao rtpengine
+// o adaptador do Prometheus (ou de qualquer outro sistema) implementa esta interface sem adicionar dependencias ao pacote
+type MetricsCollector interface {
+	// Conta o comando pelo tipo e resultado, falhas de transporte usam os resultados timeout e transport-error
+	IncCommand(command string, result string)
+	// Registra a latencia entre o envio do comando e a resposta
+	ObserveLatency(command string, latency time.Duration)
+	// Ajusta a quantidade de comandos em andamento
+	AddInFlight(delta int)
+}
+
+// Resultados usados nas metricas para falhas que não vieram do rtpengine
+const (
+	MetricsResultTimeout        = "timeout"
+	MetricsResultTransportError = "transport-error"
+)
+
+// WithClientMetrics Permite definir o coletor de metricas dos comandos
+func WithClientMetrics(metrics MetricsCollector) ClientOption {
+	return func(s *Client) error {
+		s.metrics = metrics
+		return nil
+	}
+}
+
+// Resultado do comando usado nas metricas
+func metricsResult(resposta *ResponseRtp, err error) string {
+	switch {
+	case timeoutErr(err):
+		return MetricsResultTimeout
+	case err != nil || resposta == nil:
+		return MetricsResultTransportError
+	}
+	return resposta.Result
+}
+
+// Contadores da conexão do client no momento da consulta
+type ClientStats struct {
+	CommandsSent      int64
+	ResponsesReceived int64
+	Timeouts          int64
+	Reconnects        int64
+	BytesWritten      int64
+	BytesRead         int64
+}
+
+// Contadores atualizados pelos comandos executados em paralelo
+type clientStats struct {
+	enviados   atomic.Int64
+	recebidos  atomic.Int64
+	timeouts   atomic.Int64
+	reconexoes atomic.Int64
+	escritos   atomic.Int64
+	lidos      atomic.Int64
+}
+
+// Conta a mensagem enviada
+func (s *clientStats) escrita(n int, err error) {
+	if err == nil {
+		s.enviados.Add(1)
+	}
+	s.escritos.Add(int64(n))
+}
+
+// Conta a resposta recebida ou o timeout da leitura
+func (s *clientStats) leitura(n int, err error) {
+	switch {
+	case timeoutErr(err):
+		s.timeouts.Add(1)
+	case err == nil:
+		s.recebidos.Add(1)
+	}
+	s.lidos.Add(int64(n))
+}
+
+// Stats Retorna os contadores da conexão, pode ser usado sem o MetricsCollector
+func (c *Client) Stats() ClientStats {
+	return ClientStats{
+		CommandsSent:      c.stats.enviados.Load(),
+		ResponsesReceived: c.stats.recebidos.Load(),
+		Timeouts:          c.stats.timeouts.Load(),
+		Reconnects:        c.stats.reconexoes.Load(),
+		BytesWritten:      c.stats.escritos.Load(),
+		BytesRead:         c.stats.lidos.Load(),
+	}
+}
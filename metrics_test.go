@@ -0,0 +1,95 @@
+package rtpengine
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// Coletor de metricas de teste
+type fakeMetrics struct {
+	mu        sync.Mutex
+	commands  map[string]int
+	latencias []time.Duration
+	inFlight  int
+}
+
+func (f *fakeMetrics) IncCommand(command string, result string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.commands[command+"/"+result]++
+}
+
+func (f *fakeMetrics) ObserveLatency(command string, latency time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.latencias = append(f.latencias, latency)
+}
+
+func (f *fakeMetrics) AddInFlight(delta int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inFlight += delta
+}
+
+func TestClientRequestMetrics(t *testing.T) {
+	metrics := &fakeMetrics{commands: make(map[string]int)}
+	var semResposta atomic.Bool
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		if semResposta.Load() {
+			return nil
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	}, WithClientMetrics(metrics))
+	client.timeout = 100 * time.Millisecond
+
+	require.NotNil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+	require.Equal(t, 1, metrics.commands["ping/pong"])
+
+	semResposta.Store(true)
+	require.Nil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+	require.Equal(t, 1, metrics.commands["ping/"+MetricsResultTimeout])
+	require.Equal(t, 1, metrics.commands["ping/pong"])
+
+	require.Len(t, metrics.latencias, 2)
+	require.Equal(t, 0, metrics.inFlight)
+}
+
+func TestClientRequestStats(t *testing.T) {
+	var semResposta atomic.Bool
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		if semResposta.Load() {
+			return nil
+		}
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	}, WithClientResetOnTimeout())
+	client.timeout = 100 * time.Millisecond
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			client.NewComando(&RequestRtp{Command: string(Ping)})
+		}()
+	}
+	wg.Wait()
+
+	stats := client.Stats()
+	require.Equal(t, int64(3), stats.CommandsSent)
+	require.Equal(t, int64(3), stats.ResponsesReceived)
+	// cookie uuid seguido do ping em bencode
+	require.Equal(t, int64(3*len("00000000-0000-0000-0000-000000000000 d7:command4:pinge")), stats.BytesWritten)
+	require.Greater(t, stats.BytesRead, int64(0))
+	require.Zero(t, stats.Timeouts)
+
+	semResposta.Store(true)
+	require.Nil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+	stats = client.Stats()
+	require.Equal(t, int64(4), stats.CommandsSent)
+	require.Equal(t, int64(1), stats.Timeouts)
+	require.Equal(t, int64(1), stats.Reconnects)
+}
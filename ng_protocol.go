@@ -1,11 +1,23 @@
 package rtpengine
 
-import "fmt"
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
 
 type ParametrosOption func(c *RequestRtp) error
 
 // Gera oferta do SDP com passagem de Parametros
 func SDPOffering(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	if parametros == nil {
+		return nil, fmt.Errorf("%s requer os parametros da sessão", Offer)
+	}
+
 	request := &RequestRtp{
 		Command:              fmt.Sprint(Offer),
 		ParamsOptString:      parametros,
@@ -18,11 +30,23 @@ func SDPOffering(parametros *ParamsOptString, options ...ParametrosOption) (*Req
 			return nil, err
 		}
 	}
+
+	if err := validarAddressFamily(request); err != nil {
+		return nil, err
+	}
+
+	if request.Sdp == "" {
+		return nil, fmt.Errorf("%s requer um SDP não vazio", Offer)
+	}
 	return request, nil
 }
 
 // Gera Atendimendo do SDP com passagem de Parametros
 func SDPAnswer(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	if parametros == nil {
+		return nil, fmt.Errorf("%s requer os parametros da sessão", Answer)
+	}
+
 	request := &RequestRtp{
 		Command:              fmt.Sprint(Answer),
 		ParamsOptString:      parametros,
@@ -35,6 +59,14 @@ func SDPAnswer(parametros *ParamsOptString, options ...ParametrosOption) (*Reque
 			return nil, err
 		}
 	}
+
+	if err := validarAddressFamily(request); err != nil {
+		return nil, err
+	}
+
+	if request.Sdp == "" {
+		return nil, fmt.Errorf("%s requer um SDP não vazio", Answer)
+	}
 	return request, nil
 }
 
@@ -52,9 +84,148 @@ func SDPDelete(parametros *ParamsOptString, options ...ParametrosOption) (*Reque
 			return nil, err
 		}
 	}
+
+	if err := validarAddressFamily(request); err != nil {
+		return nil, err
+	}
+	return request, nil
+}
+
+// Gera o comando list que retorna os call-ids das chamadas ativas no rtpengine
+func SDPList(options ...ParametrosOption) (*RequestRtp, error) {
+	request := &RequestRtp{
+		Command:              fmt.Sprint(List),
+		ParamsOptString:      &ParamsOptString{},
+		ParamsOptInt:         &ParamsOptInt{},
+		ParamsOptStringArray: &ParamsOptStringArray{},
+	}
+
+	for _, o := range options {
+		if err := o(request); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// Gera o silence media da chamada, o RTP continua sendo enviado com o audio substituido por silencio
+// diferente do block media que interrompe o envio
+// o escopo e definido pelo from-tag, to-tag, label ou SetAll para a chamada inteira
+func SDPSilenceMedia(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	return comandoEscopo(SilenceMedia, parametros, options...)
+}
+
+// Gera o unsilence media da chamada retomando o audio original no mesmo escopo usado no silence media
+func SDPUnsilenceMedia(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	return comandoEscopo(UnsilenceMedia, parametros, options...)
+}
+
+// Gera o start forwarding que duplica a midia da chamada para o destino externo ex: analise de audio
+// o rtpengine aceita somente um destino no output-destination, para varios destinos use o Client.StartForwarding
+// que envia um start forwarding por destino, sem from-tag encaminha todas as pernas
+func SDPStartForwarding(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	request, err := comandoChamada(StartForwarding, parametros, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.OutputDestination == "" {
+		return nil, fmt.Errorf("%s requer o destino do encaminhamento", StartForwarding)
+	}
+	return request, nil
+}
+
+// Gera o stop forwarding encerrando o encaminhamento iniciado no start forwarding com o mesmo call-id e from-tag
+func SDPStopForwarding(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	return comandoChamada(StopForwarding, parametros, options...)
+}
+
+// Gera o comando da chamada exigindo o call-id
+func comandoChamada(comando TipoComandos, parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	if parametros == nil || parametros.CallId == "" {
+		return nil, fmt.Errorf("%s requer o call-id da sessão", comando)
+	}
+
+	request := &RequestRtp{
+		Command:              fmt.Sprint(comando),
+		ParamsOptString:      parametros,
+		ParamsOptInt:         &ParamsOptInt{},
+		ParamsOptStringArray: &ParamsOptStringArray{},
+	}
+
+	for _, o := range options {
+		if err := o(request); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// Gera o comando da chamada exigindo o escopo das pernas afetadas
+func comandoEscopo(comando TipoComandos, parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	request, err := comandoChamada(comando, parametros, options...)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.FromTag == "" && request.ToTag == "" && request.Label == "" && request.All == "" {
+		return nil, fmt.Errorf("%s requer o from-tag, to-tag, label ou all", comando)
+	}
 	return request, nil
 }
 
+// Gera oferta do SDP recebida do lado WebRTC para o lado SIP em RTP/AVP
+// remove ICE, DTLS e SDES e transcodifica o opus para PCMU, usado no offer vindo do navegador
+// o answer do lado SIP volta para o WebRTC com SDPAnswer usando UDP/TLS/RTP/SAVPF e ICEForce
+func ProfilerWebRTCToSIP_Offer(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	c := &RequestRtp{}
+	profile := []ParametrosOption{
+		c.SetTransportProtocol(RTP_AVP),
+		c.ICERemove(),
+		c.SetDTLS(DTLSOff),
+		c.DesabilitarSDES(),
+		c.SetRtcpMux([]ParamRTCPMux{RTCPDemux}),
+		c.SetCodecMask([]Codecs{CODEC_OPUS}),
+		c.SetCodecEncoder([]Codecs{CODEC_PCMU}),
+	}
+	return SDPOffering(parametros, append(profile, options...)...)
+}
+
+// Gera oferta do SDP recebida do lado SIP para o lado WebRTC em UDP/TLS/RTP/SAVPF
+// força o ICE, o DTLS passivo sem SDES e transcodifica o PCMU para opus, usado no offer enviado ao navegador
+// o answer do navegador volta para o SIP com SDPAnswer usando RTP/AVP e ICERemove
+func ProfilerSIPToWebRTC_Offer(parametros *ParamsOptString, options ...ParametrosOption) (*RequestRtp, error) {
+	c := &RequestRtp{}
+	profile := []ParametrosOption{
+		c.SetTransportProtocol(UDP_TLS_RTP_SAVPF),
+		c.ICEForce(),
+		c.SetDTLS(DTLSPassive),
+		c.DesabilitarSDES(),
+		c.SetRtcpMux([]ParamRTCPMux{RTCPOffer}),
+		c.SetCodecEncoder([]Codecs{CODEC_OPUS}),
+	}
+	return SDPOffering(parametros, append(profile, options...)...)
+}
+
+// Gera oferta do SDP da sessão de gravação SIPREC com os metadados da gravação
+// a flag SIPREC apenas identifica a sessão como SIPREC, a gravação so e iniciada com a flag record-call adicionada pelo perfil
+func ProfilerSIPREC_Offer(parametros *ParamsOptString, metadata string, options ...ParametrosOption) (*RequestRtp, error) {
+	if strings.TrimSpace(metadata) == "" {
+		return nil, fmt.Errorf("%s SIPREC requer os metadados da gravação", Offer)
+	}
+
+	c := &RequestRtp{}
+	profile := []ParametrosOption{
+		c.AddFlag(SIPREC),
+		c.AddFlag(RecordCall),
+		func(s *RequestRtp) error {
+			s.Metadata = metadata
+			return nil
+		},
+	}
+	return SDPOffering(parametros, append(profile, options...)...)
+}
+
 // Adcionar um lista de flags para rtpengine
 func (c *RequestRtp) SetFlags(flags []ParamFlags) ParametrosOption {
 	return func(s *RequestRtp) error {
@@ -63,18 +234,328 @@ func (c *RequestRtp) SetFlags(flags []ParamFlags) ParametrosOption {
 	}
 }
 
-// Manipular o Transport Protocol do SDP
-func (c *RequestRtp) SetTransportProtocol(proto TransportProtocol) ParametrosOption {
+// Adicionar a flag caso ainda não exista
+func (c *RequestRtp) AddFlag(flag ParamFlags) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.ParamsOptStringArray == nil {
+			s.ParamsOptStringArray = &ParamsOptStringArray{}
+		}
+
+		if !s.HasFlag(flag) {
+			s.Flags = append(s.Flags, flag)
+		}
+		return nil
+	}
+}
+
+// Remover todas as ocorrencias da flag, sem efeito caso a flag não exista
+func (c *RequestRtp) RemoveFlag(flag ParamFlags) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.ParamsOptStringArray == nil {
+			return nil
+		}
+
+		flags := make([]ParamFlags, 0, len(s.Flags))
+		for _, o := range s.Flags {
+			if o != flag {
+				flags = append(flags, o)
+			}
+		}
+		s.Flags = flags
+		return nil
+	}
+}
+
+// Verifica se a flag esta presente no comando
+func (c *RequestRtp) HasFlag(flag ParamFlags) bool {
+	if c.ParamsOptStringArray == nil {
+		return false
+	}
+
+	for _, o := range c.Flags {
+		if o == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Enviar a midia em um sentido apenas, usado nos anuncios e URA onde somente a midia para o chamador e enviada
+// pode ser combinado com os comandos block media e silence media
+func (c *RequestRtp) Unidirectional() ParametrosOption {
+	return c.AddFlag(Unidirectional)
+}
+
+// Aplicar o comando na midia de saida do rtpengine em vez da midia recebida
+func (c *RequestRtp) Egress() ParametrosOption {
+	return c.AddFlag(Egress)
+}
+
+// Interromper a midia de saida mantendo a sessão ativa, pode ser combinado com os comandos block media e silence media
+func (c *RequestRtp) BlockEgress() ParametrosOption {
+	return c.AddFlag(BlockEgress)
+}
+
+// Aceitar somente um codec no answer, usado em gateways simples, pode ser combinado com NoCodecRenegotiation
+func (c *RequestRtp) SingleCodec() ParametrosOption {
+	return c.AddFlag(SingleCodec)
+}
+
+// Manter a lista de codecs do offer sem alterar a ordem nem remover codecs nos re-offers
+func (c *RequestRtp) StaticCodecs() ParametrosOption {
+	return c.AddFlag(StaticCodecs)
+}
+
+// Reaproveitar os codecs negociados anteriormente no re-offer em vez de negociar de novo
+func (c *RequestRtp) ReuseCodecs() ParametrosOption {
+	return c.AddFlag(ReuseCodecs)
+}
+
+// Fixar os codecs negociados no primeiro offer/answer nos re-INVITEs seguintes
+// com SingleCodec o codec unico escolhido no primeiro answer e mantido
+func (c *RequestRtp) NoCodecRenegotiation() ParametrosOption {
+	return c.AddFlag(NoCodecRenegotiation)
+}
+
+// Gerar o atributo a=mid nas midias sem mid, no BUNDLE do WebRTC cada m-line precisa de um mid para ser agrupada
+func (c *RequestRtp) GenerateMid() ParametrosOption {
+	return c.AddFlag(GenerateMid)
+}
+
+// Marcar o offer como fragmento do offer completo, usado no trickle ICE onde os candidatos chegam depois do SDP
+func (c *RequestRtp) Fragment() ParametrosOption {
+	return c.AddFlag(Fragment)
+}
+
+// Remover os atributos a=extmap do SDP, usado com endpoints que não aceitam as extensões do cabeçalho RTP do WebRTC
+func (c *RequestRtp) StripExtmap() ParametrosOption {
+	return c.AddFlag(StripExtmap)
+}
+
+// Não incluir o atributo a=rtcp no SDP reescrito, para endpoints que rejeitam o atributo, não pode ser usado com FullRTCPAttribute
+func (c *RequestRtp) NoRTCPAttribute() ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.HasFlag(FullRtcpAttribute) {
+			return fmt.Errorf("flag %s não pode ser usada com %s", NoRtcpAttribute, FullRtcpAttribute)
+		}
+		return c.AddFlag(NoRtcpAttribute)(s)
+	}
+}
+
+// Incluir o a=rtcp com o endereço completo no SDP reescrito, não pode ser usado com NoRTCPAttribute
+func (c *RequestRtp) FullRTCPAttribute() ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.HasFlag(NoRtcpAttribute) {
+			return fmt.Errorf("flag %s não pode ser usada com %s", FullRtcpAttribute, NoRtcpAttribute)
+		}
+		return c.AddFlag(FullRtcpAttribute)(s)
+	}
+}
+
+// Enviar a midia recebida antes do answer (early media) para o originador
+func (c *RequestRtp) EarlyMedia() ParametrosOption {
+	return c.AddFlag(EarlyMedia)
+}
+
+// Repassar a midia sem alterações, o rtpengine não processa os pacotes, não pode ser usado com NoPassthrough
+func (c *RequestRtp) Passthrough() ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.HasFlag(NoPassthrough) {
+			return fmt.Errorf("flag %s não pode ser usada com %s", Passthrough, NoPassthrough)
+		}
+		return c.AddFlag(Passthrough)(s)
+	}
+}
+
+// Desativar o passthrough configurado no rtpengine, não pode ser usado com Passthrough
+func (c *RequestRtp) NoPassthrough() ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.HasFlag(Passthrough) {
+			return fmt.Errorf("flag %s não pode ser usada com %s", NoPassthrough, Passthrough)
+		}
+		return c.AddFlag(NoPassthrough)(s)
+	}
+}
+
+// Aceitar a mudança do endereço de origem da midia sem renegociação ex: troca de Wi-Fi para rede celular
+// a migração e feita com um re-offer com o mesmo call-id e from-tag, a flag media-handover e o novo
+// endereço em SetMediaAddress ou no SDP, seguido do answer com o mesmo to-tag
+func (c *RequestRtp) MediaHandover() ParametrosOption {
+	return c.AddFlag(MediaHandover)
+}
+
+// Adicionar ou remover a flag loop-protect, desativada nas topologias com loop de midia intencional ex: testes de eco
+// os builders recebem a opção no final da lista para desativar a flag adicionada antes, o loop-protect bloquearia
+// o eco da midia então não pode ser ativado com o media-echo
+func (c *RequestRtp) LoopProtect(enabled bool) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if !enabled {
+			return c.RemoveFlag(LoopProtect)(s)
+		}
+
+		if s.ParamsOptString != nil && s.MediaEcho != "" {
+			return fmt.Errorf("loop-protect não pode ser usado com o media-echo %s", s.MediaEcho)
+		}
+		return c.AddFlag(LoopProtect)(s)
+	}
+}
+
+// Desativar o jitter buffer na chamada
+func (c *RequestRtp) NoJitterBuffer() ParametrosOption {
+	return c.AddFlag(NoJitterBuffer)
+}
+
+// Definir o tamanho do delay-buffer, um buffer maior ajuda nas redes com perda e um menor reduz a latencia
+// não pode ser usado com DisableJitterBuffer
+func (c *RequestRtp) JitterBuffer(packets int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if packets <= 0 {
+			return fmt.Errorf("delay-buffer %d invalido", packets)
+		}
+
+		if s.HasFlag(NoJitterBuffer) {
+			return fmt.Errorf("delay-buffer não pode ser usado com a flag %s", NoJitterBuffer)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+		s.DelayBuffer = packets
+		return nil
+	}
+}
+
+// Desativar o jitter buffer para a menor latencia ex: conferencia, não pode ser usado com JitterBuffer
+func (c *RequestRtp) DisableJitterBuffer() ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.ParamsOptInt != nil && s.DelayBuffer != 0 {
+			return fmt.Errorf("flag %s não pode ser usada com o delay-buffer %d", NoJitterBuffer, s.DelayBuffer)
+		}
+		return c.NoJitterBuffer()(s)
+	}
+}
+
+// Confiar no endereço do SDP ignorando o endereço de origem dos pacotes
+func (c *RequestRtp) TrustAddress() ParametrosOption {
+	return c.AddFlag(TrustAddress)
+}
+
+// Enviar a midia para o endereço de origem aprendido nos pacotes recebidos
+func (c *RequestRtp) Symmetric() ParametrosOption {
+	return c.AddFlag(Symmetric)
+}
+
+// Enviar a midia para o endereço do SDP mesmo recebendo de outro endereço
+func (c *RequestRtp) Asymmetric() ParametrosOption {
+	return c.AddFlag(Asymmetric)
+}
+
+// Descartar a midia recebida de endereço diferente do aprendido
+func (c *RequestRtp) StrictSource() ParametrosOption {
+	return c.AddFlag(StrictSource)
+}
+
+// Enviar pacotes para o endpoint abrindo o pinhole do NAT antes de receber midia
+func (c *RequestRtp) PierceNAT() ParametrosOption {
+	return c.AddFlag(PierceNAT)
+}
+
+// Definir o conjunto de flags de NAT: NATBehind (symmetric e pierce-NAT), NATTrusted (trust-address e asymmetric)
+// ou NATStrict (symmetric e strict-source)
+func (c *RequestRtp) NATMode(mode NATMode) ParametrosOption {
 	return func(s *RequestRtp) error {
-		s.TransportProtocol = proto
+		var flags []ParametrosOption
+		switch mode {
+		case NATBehind:
+			flags = []ParametrosOption{c.Symmetric(), c.PierceNAT()}
+		case NATTrusted:
+			flags = []ParametrosOption{c.TrustAddress(), c.Asymmetric()}
+		case NATStrict:
+			flags = []ParametrosOption{c.Symmetric(), c.StrictSource()}
+		default:
+			return fmt.Errorf("modo de NAT %q desconhecido", mode)
+		}
+
+		for _, o := range flags {
+			if err := o(s); err != nil {
+				return err
+			}
+		}
 		return nil
 	}
 }
 
-// Adiciona flags de manipulação
+// Manipular o Transport Protocol do SDP, valor vazio mantem o protocolo atual
+func (c *RequestRtp) SetTransportProtocol(proto TransportProtocol) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if proto == "" {
+			return nil
+		}
+
+		for _, valid := range ValidTransportProtocols() {
+			if proto == valid {
+				s.TransportProtocol = proto
+				return nil
+			}
+		}
+		return fmt.Errorf("transport-protocol %q desconhecido, valores aceitos: %v", proto, ValidTransportProtocols())
+	}
+}
+
+// Adiciona flags de manipulação, o session-connection não e mais suportado pelo rtpengine e sera ignorado
+// o alias force-increment-sdp-ver e enviado como force-increment-sdp-version
 func (c *RequestRtp) SetReplace(replace []ParamReplace) ParametrosOption {
 	return func(s *RequestRtp) error {
-		s.Replace = replace
+		s.Replace = make([]ParamReplace, 0)
+		for _, o := range replace {
+			switch o {
+			case SessionConnection:
+				continue
+			case ForceIncrementSdpVer:
+				o = ForceIncrementSdpVersion
+			}
+			addReplace(s, o)
+		}
+		return nil
+	}
+}
+
+// Substituir o campo origin (o=) do SDP
+func (c *RequestRtp) ReplaceOrigin() ParametrosOption {
+	return func(s *RequestRtp) error {
+		addReplace(s, Origin)
+		return nil
+	}
+}
+
+// Substituir o nome da sessão (s=) do SDP
+func (c *RequestRtp) ReplaceSessionName() ParametrosOption {
+	return func(s *RequestRtp) error {
+		addReplace(s, SessionName)
+		return nil
+	}
+}
+
+// Substituir o username do campo origin do SDP
+func (c *RequestRtp) ReplaceUsername() ParametrosOption {
+	return func(s *RequestRtp) error {
+		addReplace(s, Username)
+		return nil
+	}
+}
+
+// Substituir o endereço zero (0.0.0.0) do SDP
+func (c *RequestRtp) ReplaceZeroAddress() ParametrosOption {
+	return func(s *RequestRtp) error {
+		addReplace(s, ZeroAddress)
+		return nil
+	}
+}
+
+// Forçar o incremento da versão do SDP
+func (c *RequestRtp) ForceIncrementSDPVersion() ParametrosOption {
+	return func(s *RequestRtp) error {
+		addReplace(s, ForceIncrementSdpVersion)
 		return nil
 	}
 }
@@ -126,6 +607,24 @@ func (c *RequestRtp) SetCodecStrip(codecs []Codecs) ParametrosOption {
 	}
 }
 
+// Remover todos os codecs da lista do SDP
+// o rtpengine aplica as flags na ordem enviada, use antes do SetCodecExcept para manter somente os codecs desejados
+func (c *RequestRtp) SetCodecStripAll() ParametrosOption {
+	return func(s *RequestRtp) error {
+		s.ParamsOptStringArray.Flags = append(s.ParamsOptStringArray.Flags, CodecStripAll)
+		return nil
+	}
+}
+
+// Mascarar todos os codecs da lista do SDP
+// o rtpengine aplica as flags na ordem enviada, use antes do SetCodecExcept para manter somente os codecs desejados
+func (c *RequestRtp) SetCodecMaskAll() ParametrosOption {
+	return func(s *RequestRtp) error {
+		s.ParamsOptStringArray.Flags = append(s.ParamsOptStringArray.Flags, CodecMaskAll)
+		return nil
+	}
+}
+
 // Bloquear todos os codecs, exceto aqueles fornecidos na lista de permissões.
 func (c *RequestRtp) SetCodecExcept(codecs []Codecs) ParametrosOption {
 	return func(s *RequestRtp) error {
@@ -139,55 +638,180 @@ func (c *RequestRtp) SetCodecExcept(codecs []Codecs) ParametrosOption {
 	}
 }
 
-// Desabilitar a criptografia SDES na oferta
-func (c *RequestRtp) DesabilitarSDES() ParametrosOption {
+// Manipular codecs adiciona na lista do SDP do offer
+func (c *RequestRtp) SetCodecOffer(codecs []Codecs) ParametrosOption {
 	return func(s *RequestRtp) error {
-		sdes := make([]SDES, 0)
-		sdes = append(sdes, SDESOff)
-		s.ParamsOptStringArray.SDES = append(s.ParamsOptStringArray.SDES, sdes...)
+		offer := make([]ParamFlags, 0)
+		for _, o := range codecs {
+			offer = append(offer, ParamFlags("codec-offer-"+o))
+		}
+
+		s.ParamsOptStringArray.Flags = append(s.ParamsOptStringArray.Flags, offer...)
 		return nil
 	}
 }
 
-// Excluir pacotes de criptografia individuais
-func (c *RequestRtp) DeletesSDES(cript []CryptoSuite) ParametrosOption {
+// Manipular codecs aceita no answer mesmo que não tenham sido oferecidos
+func (c *RequestRtp) SetCodecAccept(codecs []Codecs) ParametrosOption {
 	return func(s *RequestRtp) error {
-		sdes := make([]SDES, 0)
-		for _, o := range cript {
-			sdes = append(sdes, "no-"+SDES(o))
+		accept := make([]ParamFlags, 0)
+		for _, o := range codecs {
+			accept = append(accept, ParamFlags("codec-accept-"+o))
 		}
-		s.ParamsOptStringArray.SDES = append(s.ParamsOptStringArray.SDES, sdes...)
+
+		s.ParamsOptStringArray.Flags = append(s.ParamsOptStringArray.Flags, accept...)
 		return nil
 	}
 }
 
-// Permitir apenas o pacotes de criptografia individuais
-func (c *RequestRtp) EnableSDES(cript []CryptoSuite) ParametrosOption {
+// Manipular codecs consome o codec sem repassar para o outro lado
+func (c *RequestRtp) SetCodecConsume(codecs []Codecs) ParametrosOption {
 	return func(s *RequestRtp) error {
-		sdes := make([]SDES, 0)
-		for _, o := range cript {
-			sdes = append(sdes, "only-"+SDES(o))
+		consume := make([]ParamFlags, 0)
+		for _, o := range codecs {
+			consume = append(consume, ParamFlags("codec-consume-"+o))
 		}
-		s.ParamsOptStringArray.SDES = append(s.ParamsOptStringArray.SDES, sdes...)
+
+		s.ParamsOptStringArray.Flags = append(s.ParamsOptStringArray.Flags, consume...)
 		return nil
 	}
 }
 
-// Qualquer atributos do ICE será removido do corpo do SDP
-func (c *RequestRtp) ICERemove() ParametrosOption {
+// Manipular codecs define os parametros do codec, a especificação e informada no formato bruto ex: opus/48000/2
+func (c *RequestRtp) SetCodecSet(specs []string) ParametrosOption {
 	return func(s *RequestRtp) error {
-		s.ICE = "remove"
+		set := make([]ParamFlags, 0)
+		for _, o := range specs {
+			set = append(set, ParamFlags("codec-set-"+o))
+		}
+
+		s.ParamsOptStringArray.Flags = append(s.ParamsOptStringArray.Flags, set...)
 		return nil
 	}
 }
 
-// Os atributos do ICE são primeiro removidos e, em seguida, novos atributos são gerados e inseridos, o que deixa o proxy de mídia como o único candidato ao ICE
-func (c *RequestRtp) ICEForce() ParametrosOption {
+// Definir os parametros do opus no codec-set ex: opus/48000/1/16000/maxplaybackrate=16000;cbr=1
+// usado para forçar o opus com bitrate baixo e constante para um cliente com banda limitada
+func (c *RequestRtp) SetOpusCodec(opus OpusOptions) ParametrosOption {
 	return func(s *RequestRtp) error {
-		s.ICE = "force"
-		return nil
-	}
-}
+		channels := opus.Channels
+		if channels == 0 {
+			channels = 2
+		}
+
+		if channels != 1 && channels != 2 {
+			return fmt.Errorf("opus com %d canais invalido", opus.Channels)
+		}
+
+		if opus.Bitrate != 0 && (opus.Bitrate < 6000 || opus.Bitrate > 510000) {
+			return fmt.Errorf("opus bitrate %d fora do intervalo 6000-510000", opus.Bitrate)
+		}
+
+		if opus.MaxPlaybackRate != 0 && (opus.MaxPlaybackRate < 8000 || opus.MaxPlaybackRate > 48000) {
+			return fmt.Errorf("opus maxplaybackrate %d fora do intervalo 8000-48000", opus.MaxPlaybackRate)
+		}
+
+		fmtp := make([]string, 0)
+		if opus.MaxPlaybackRate != 0 {
+			fmtp = append(fmtp, "maxplaybackrate="+strconv.Itoa(opus.MaxPlaybackRate))
+		}
+		if opus.CBR {
+			fmtp = append(fmtp, "cbr=1")
+		}
+		if opus.UseInbandFEC {
+			fmtp = append(fmtp, "useinbandfec=1")
+		}
+
+		spec := fmt.Sprintf("%s/48000/%d", CODEC_OPUS, channels)
+		if opus.Bitrate != 0 || len(fmtp) > 0 {
+			spec += "/" + strconv.Itoa(opus.Bitrate)
+		}
+		if len(fmtp) > 0 {
+			spec += "/" + strings.Join(fmtp, ";")
+		}
+		return c.SetCodecSet([]string{spec})(s)
+	}
+}
+
+// Desabilitar a criptografia SDES na oferta
+func (c *RequestRtp) DesabilitarSDES() ParametrosOption {
+	return func(s *RequestRtp) error {
+		sdes := make([]SDES, 0)
+		sdes = append(sdes, SDESOff)
+		s.ParamsOptStringArray.SDES = append(s.ParamsOptStringArray.SDES, sdes...)
+		return nil
+	}
+}
+
+// Excluir pacotes de criptografia individuais
+func (c *RequestRtp) DeletesSDES(cript []CryptoSuite) ParametrosOption {
+	return func(s *RequestRtp) error {
+		sdes := make([]SDES, 0)
+		for _, o := range cript {
+			sdes = append(sdes, "no-"+SDES(o))
+		}
+		s.ParamsOptStringArray.SDES = append(s.ParamsOptStringArray.SDES, sdes...)
+		return nil
+	}
+}
+
+// Permitir apenas o pacotes de criptografia individuais
+func (c *RequestRtp) EnableSDES(cript []CryptoSuite) ParametrosOption {
+	return func(s *RequestRtp) error {
+		sdes := make([]SDES, 0)
+		for _, o := range cript {
+			sdes = append(sdes, "only-"+SDES(o))
+		}
+		s.ParamsOptStringArray.SDES = append(s.ParamsOptStringArray.SDES, sdes...)
+		return nil
+	}
+}
+
+// Qualquer atributos do ICE será removido do corpo do SDP
+func (c *RequestRtp) ICERemove() ParametrosOption {
+	return func(s *RequestRtp) error {
+		s.ICE = "remove"
+		return nil
+	}
+}
+
+// Os atributos do ICE são primeiro removidos e, em seguida, novos atributos são gerados e inseridos, o que deixa o proxy de mídia como o único candidato ao ICE
+func (c *RequestRtp) ICEForce() ParametrosOption {
+	return func(s *RequestRtp) error {
+		s.ICE = "force"
+		return nil
+	}
+}
+
+// Definir o modo do ICE: remove, force, default, force-relay ou optional
+// force-relay usa somente os candidatos relay, necessario em implantações somente com TURN
+func (c *RequestRtp) SetICE(mode ICE) ParametrosOption {
+	return func(s *RequestRtp) error {
+		switch mode {
+		case ICERemove, ICEForce, ICEDefault, ICEForceRelay, ICEOptional:
+			s.ICE = mode
+			return nil
+		}
+		return fmt.Errorf("ICE %q desconhecido", mode)
+	}
+}
+
+// Definir o comportamento do DTLS no lado de saida ex: off, passive, active
+func (c *RequestRtp) SetDTLS(dtls DTLS) ParametrosOption {
+	return func(s *RequestRtp) error {
+		s.DTLS = dtls
+		return nil
+	}
+}
+
+// Remove os atributos do ICE mantendo o DTLS configurado, usado na interoperação DTLS-SRTP sem ICE
+// o DTLS e o replace informados nos parametros não são alterados
+func (c *RequestRtp) StripICEKeepDTLS() ParametrosOption {
+	return func(s *RequestRtp) error {
+		s.ICE = ICERemove
+		return nil
+	}
+}
 
 // Manipulador de atributos do SDP suporta adicionar, remover e substituir
 func (c *RequestRtp) SetAttrChange(sdpAttr *ParamsSdpAttrSections) ParametrosOption {
@@ -197,14 +821,111 @@ func (c *RequestRtp) SetAttrChange(sdpAttr *ParamsSdpAttrSections) ParametrosOpt
 	}
 }
 
-// Manipulador de atributos do SDP suporta adicionar, remover e substituir
+// Adicionar um atributo na seção do SDP (global, audio, video, none)
+func (c *RequestRtp) AddSdpAttr(section string, attr string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		comandos, err := sdpAttrSection(s, section)
+		if err != nil {
+			return err
+		}
+		comandos.Add = append(comandos.Add, attr)
+		return nil
+	}
+}
+
+// Remover um atributo da seção do SDP (global, audio, video, none)
+func (c *RequestRtp) RemoveSdpAttr(section string, attr string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		comandos, err := sdpAttrSection(s, section)
+		if err != nil {
+			return err
+		}
+		comandos.Remove = append(comandos.Remove, attr)
+		return nil
+	}
+}
+
+// Substituir um atributo da seção do SDP (global, audio, video, none)
+func (c *RequestRtp) SubstituteSdpAttr(section string, from string, to string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		comandos, err := sdpAttrSection(s, section)
+		if err != nil {
+			return err
+		}
+		comandos.Substitute = append(comandos.Substitute, []string{from, to})
+		return nil
+	}
+}
+
+// Substituir os atributos da seção do SDP (global, audio, video) que correspondem a expressão regular
+// o rtpengine aceita somente pares literais no substitute, a expressão e aplicada nos atributos do SDP do comando
+// e cada atributo alterado gera um par literal, use depois do SDP definido no comando, add e remove continuam literais
+func (c *RequestRtp) SetSdpAttrSubstituteRegex(section string, pattern string, replacement string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("sdp-attr expressão %q invalida: %w", pattern, err)
+		}
+
+		if section == "none" {
+			return fmt.Errorf("sdp-attr seção %q não suporta expressão regular", section)
+		}
+
+		comandos, err := sdpAttrSection(s, section)
+		if err != nil {
+			return err
+		}
+
+		atual := "global"
+		for _, linha := range strings.Split(NormalizeSDP(s.Sdp), "\r\n") {
+			if strings.HasPrefix(linha, "m=") {
+				atual, _, _ = strings.Cut(linha[2:], " ")
+				continue
+			}
+
+			if atual != section || !strings.HasPrefix(linha, "a=") || !re.MatchString(linha[2:]) {
+				continue
+			}
+
+			par := []string{linha[2:], re.ReplaceAllString(linha[2:], replacement)}
+			if par[0] != par[1] && !sdpAttrSubstituido(comandos, par[0]) {
+				comandos.Substitute = append(comandos.Substitute, par)
+			}
+		}
+		return nil
+	}
+}
+
+// Definir o via-branch da transação SIP, no forking serial ou paralelo o rtpengine usa o via-branch para separar
+// os offers com o mesmo call-id e from-tag, branch sem o magic cookie z9hG4bK da RFC 3261 e aceito e o client
+// registra um aviso no envio do comando
 func (c *RequestRtp) SetViaBranchTag(branch string) ParametrosOption {
 	return func(s *RequestRtp) error {
+		if branch == "" {
+			return fmt.Errorf("via-branch vazio")
+		}
 		s.ViaBranch = branch
 		return nil
 	}
 }
 
+// Adicionar os from-tags dos comandos com varias pernas ex: subscribe request de varias origens no mixer de conferencia
+func (c *RequestRtp) SetFromTags(tags []string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		for _, tag := range tags {
+			if strings.TrimSpace(tag) == "" {
+				return fmt.Errorf("from-tags com tag vazia")
+			}
+		}
+
+		if s.ParamsOptStringArray == nil {
+			s.ParamsOptStringArray = &ParamsOptStringArray{}
+		}
+		s.FromTags = append(s.FromTags, tags...)
+		return nil
+	}
+}
+
 // Adicionar o valor de ptime do codec no offer valor a ser utilizado e inteiro
 func (c *RequestRtp) SetPtimeCodecOffer(ptime int) ParametrosOption {
 	return func(s *RequestRtp) error {
@@ -221,18 +942,697 @@ func (c *RequestRtp) SetPtimeCodecAnswer(ptime int) ParametrosOption {
 	}
 }
 
+// Adicionar o mesmo valor de ptime no offer e no answer, valor em ms entre 10 e 60
+func (c *RequestRtp) SetPtimeSymmetric(ptime int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if ptime < 10 || ptime > 60 {
+			return fmt.Errorf("ptime %d ms fora da faixa de 10 a 60 ms", ptime)
+		}
+		s.Ptime = ptime
+		s.PtimeReverse = ptime
+		return nil
+	}
+}
+
 // Adicionar o received-from Usado se os endereços SDP não forem confiáveis
+// o valor e enviado como o par [familia, endereço] e o endereço deve pertencer a familia informada,
+// o rtpengine aceita somente um par então a ultima chamada substitui a anterior
 func (c *RequestRtp) SetReceivedFrom(addressFamily AddressFamily, Address string) ParametrosOption {
 	return func(s *RequestRtp) error {
-		receivedFrom := make([]string, 0)
-		s.ReceivedFrom = append(receivedFrom, string(addressFamily), Address)
+		ip := net.ParseIP(Address)
+		if ip == nil {
+			return fmt.Errorf("received-from %s não e um endereço ip valido", Address)
+		}
+
+		if addressFamily != AddressFamilyIP4 && addressFamily != AddressFamilyIP6 {
+			return fmt.Errorf("received-from familia de endereço %s desconhecida", addressFamily)
+		}
+
+		if addressFamilyIP(ip) != addressFamily {
+			return fmt.Errorf("received-from endereço %s não pertence a familia %s", Address, addressFamily)
+		}
+
+		s.ReceivedFrom = []string{string(addressFamily), Address}
 		return nil
 	}
 }
 
+// Adicionar o received-from a partir do net.IP com a familia IP4 ou IP6 definida pelo proprio endereço, substitui o par anterior
+func (c *RequestRtp) SetReceivedFromIP(addr net.IP) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if len(addr) != net.IPv4len && len(addr) != net.IPv6len {
+			return fmt.Errorf("received-from endereço %v invalido", addr)
+		}
+		return c.SetReceivedFrom(addressFamilyIP(addr), addr.String())(s)
+	}
+}
+
+// Definir o endereço da midia, o IPv6 copiado do SDP com colchetes e aceito e enviado sem eles
+// o address-family e definido pelo endereço quando não informado
 func (c *RequestRtp) SetMediaAddress(Address string) ParametrosOption {
 	return func(s *RequestRtp) error {
-		s.MediaAddress = Address
+		ip := net.ParseIP(strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(Address), "["), "]"))
+		if ip == nil {
+			return fmt.Errorf("media-address %q não e um endereço IP", Address)
+		}
+
+		s.MediaAddress = ip.String()
+		if s.AddressFamily == "" {
+			s.AddressFamily = addressFamilyIP(ip)
+		}
+		return nil
+	}
+}
+
+// Adicionar as opções de T.38, aceito somente nos comandos offer e answer
+func (c *RequestRtp) SetT38(options []T38) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.Command != string(Offer) && s.Command != string(Answer) {
+			return fmt.Errorf("T.38 não e aceito no comando %s", s.Command)
+		}
+		for _, o := range options {
+			s.ParamsOptStringArray.T38 = append(s.ParamsOptStringArray.T38, string(o))
+		}
 		return nil
 	}
 }
+
+// Adicionar a lista de frequencias em Hz dos tons gerados pelo rtpengine ex: [350, 440] para o tom de discagem
+func (c *RequestRtp) SetFrequencies(freqs []int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		frequencies := make([]string, 0)
+		for _, o := range freqs {
+			if err := validarFrequencia(o); err != nil {
+				return err
+			}
+			frequencies = append(frequencies, strconv.Itoa(o))
+		}
+
+		s.ParamsOptStringArray.Frequencies = append(s.ParamsOptStringArray.Frequencies, frequencies...)
+		return nil
+	}
+}
+
+// Adicionar a frequencia em Hz do tom gerado pelo rtpengine
+func (c *RequestRtp) SetFrequency(freq int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if err := validarFrequencia(freq); err != nil {
+			return err
+		}
+		s.Frequency = strconv.Itoa(freq)
+		return nil
+	}
+}
+
+// Adicionar a musica em espera a partir de um arquivo, o modo padrão e sendonly
+func (c *RequestRtp) SetMohFile(file string, mode ...string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		moh, err := mohOrigem(s, "file")
+		if err != nil {
+			return err
+		}
+
+		moh.File = file
+		moh.Mode = "sendonly"
+		if len(mode) > 0 {
+			moh.Mode = mode[0]
+		}
+		return nil
+	}
+}
+
+// Adicionar a musica em espera a partir do conteudo do arquivo
+func (c *RequestRtp) SetMohBlob(blob string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		moh, err := mohOrigem(s, "blob")
+		if err != nil {
+			return err
+		}
+		moh.Blob = blob
+		return nil
+	}
+}
+
+// Adicionar a musica em espera a partir do id da midia no banco de dados
+func (c *RequestRtp) SetMohDbId(id string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		moh, err := mohOrigem(s, "db-id")
+		if err != nil {
+			return err
+		}
+		moh.DbId = id
+		return nil
+	}
+}
+
+// Definir o modo da musica em espera ex: sendonly, sendrecv
+func (c *RequestRtp) SetMohMode(mode string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.Moh == nil {
+			s.Moh = &ParamMoh{}
+		}
+		s.Moh.Mode = mode
+		return nil
+	}
+}
+
+// Definir a conexão da musica em espera, MohConnection (zero) coloca o endereço de conexão zerado no hold
+func (c *RequestRtp) SetMohConnection(connection Connection) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.Moh == nil {
+			s.Moh = &ParamMoh{}
+		}
+		s.Moh.Connection = connection
+		return nil
+	}
+}
+
+// Definir o destino da gravação da chamada usado no start recording ou no offer com record-call
+func (c *RequestRtp) SetOutputDestination(dest string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if strings.TrimSpace(dest) == "" {
+			return fmt.Errorf("output-destination vazio")
+		}
+		s.OutputDestination = dest
+		return nil
+	}
+}
+
+// Definir a url http ou https que recebe os eventos da chamada via xmlrpc ex: timeout e teardown
+func (c *RequestRtp) SetXmlrpcCallback(callback string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		u, err := url.Parse(callback)
+		if err != nil {
+			return fmt.Errorf("xmlrpc-callback %q invalido: %w", callback, err)
+		}
+
+		if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("xmlrpc-callback %q requer uma url http ou https", callback)
+		}
+		s.XmlrpcCallback = callback
+		return nil
+	}
+}
+
+// Definir o nome do template de parametros configurado no rtpengine, template desconhecido retorna a resposta de erro do rtpengine
+func (c *RequestRtp) SetTemplate(name string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("template vazio")
+		}
+		s.Template = name
+		return nil
+	}
+}
+
+// Definir o id da midia carregada no banco do rtpengine usado no play media, os ids começam em 1 e o 0 não e enviado
+func (c *RequestRtp) SetDbId(id int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if id < 0 {
+			return fmt.Errorf("db-id %d invalido", id)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+		s.ParamsOptInt.DbId = id
+		return nil
+	}
+}
+
+// Definir o byte TOS dos pacotes de midia enviados pelo rtpengine
+func (c *RequestRtp) SetTOS(tos int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if tos < 0 || tos > 255 {
+			return fmt.Errorf("TOS %d invalido", tos)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+		s.TOS = tos
+		return nil
+	}
+}
+
+// Definir o TOS pela classe DSCP ex: EF para voz (184), AF41 para video
+func (c *RequestRtp) SetDSCP(class string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		dscp, ok := dscpClasses[strings.ToUpper(class)]
+		if !ok {
+			return fmt.Errorf("classe DSCP %q desconhecida", class)
+		}
+		return c.SetTOS(dscp << 2)(s)
+	}
+}
+
+// Forçar o SSRC usado pelo rtpengine na midia, o SSRC negociado e lido na resposta pelo SSRCs
+func (c *RequestRtp) SetSSRC(values ...uint32) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if len(values) == 0 {
+			return fmt.Errorf("SSRC vazio")
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+		s.ParamsOptInt.SSRC = SSRCList(values)
+		return nil
+	}
+}
+
+// Definir a posição inicial em milissegundos da midia do play media
+func (c *RequestRtp) SetStartPos(pos int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if pos < 0 {
+			return fmt.Errorf("start-pos %d invalido", pos)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+		s.StartPos = pos
+		return nil
+	}
+}
+
+// Definir quantas vezes a midia do play media e repetida, não pode ser usado junto com repeat-duration
+func (c *RequestRtp) SetRepeatTimes(times int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if times < 1 {
+			return fmt.Errorf("repeat-times %d invalido", times)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+
+		if s.RepeatDuration != 0 {
+			return fmt.Errorf("repeat-times não pode ser usado junto com repeat-duration")
+		}
+		s.RepeatTimes = times
+		return nil
+	}
+}
+
+// Definir por quantos milissegundos a midia do play media e repetida, não pode ser usado junto com repeat-times
+func (c *RequestRtp) SetRepeatDuration(duration int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if duration < 1 {
+			return fmt.Errorf("repeat-duration %d invalido", duration)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+
+		if s.RepeatTimes != 0 {
+			return fmt.Errorf("repeat-duration não pode ser usado junto com repeat-times")
+		}
+		s.RepeatDuration = duration
+		return nil
+	}
+}
+
+// Definir a interface logica do rtpengine usada na midia do offer ex: internal, external
+func (c *RequestRtp) SetInterface(name string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if strings.TrimSpace(name) == "" {
+			return fmt.Errorf("interface vazia")
+		}
+		s.Interface = MediaInterface{name}
+		return nil
+	}
+}
+
+// Definir o par de interfaces logicas [entrada, saida] usado na ponte entre interfaces com NAT
+func (c *RequestRtp) SetInterfacePair(in, out string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if strings.TrimSpace(in) == "" || strings.TrimSpace(out) == "" {
+			return fmt.Errorf("par de interfaces [%s, %s] invalido", in, out)
+		}
+		s.Interface = MediaInterface{in, out}
+		return nil
+	}
+}
+
+// Definir a direção [entrada, saida] das interfaces da midia ex: private, public no SBC com topology hiding
+func (c *RequestRtp) SetDirection(in, out string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if strings.TrimSpace(in) == "" || strings.TrimSpace(out) == "" {
+			return fmt.Errorf("direction requer duas interfaces, recebido [%s, %s]", in, out)
+		}
+		s.Direction = []string{in, out}
+		return nil
+	}
+}
+
+// Definir o parametro all que seleciona as pernas afetadas pelo comando, valores aceitos: all, none, offer-answer, except-offer-answer e flows
+// no block/unblock media, block/unblock DTMF e silence media o all aplica em todas as pernas da chamada ignorando o from-tag,
+// no delete e no query o all seleciona todas as tags da chamada e o none somente a tag informada
+func (c *RequestRtp) SetAll(value string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		for _, o := range allValues {
+			if o == value {
+				s.All = value
+				return nil
+			}
+		}
+		return fmt.Errorf("all %q invalido, valores aceitos: %v", value, allValues)
+	}
+}
+
+// Definir a midia do play media enviada no proprio comando sem arquivo no disco
+// o bencode transmite a string como bytes com o tamanho no prefixo, os dados binarios são enviados sem base64
+func (c *RequestRtp) SetBlob(data []byte) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if len(data) == 0 {
+			return fmt.Errorf("blob vazio")
+		}
+		s.Blob = string(data)
+		return nil
+	}
+}
+
+// Adicionar o tempo em segundos para remover a sessão no delete, 0 remove imediatamente
+func (c *RequestRtp) SetDeleteDelay(delay int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if delay < 0 {
+			return fmt.Errorf("delete-delay %d invalido", delay)
+		}
+		s.DeleteDelay = delay
+		s.deleteImediato = delay == 0
+		return nil
+	}
+}
+
+// Delete de chamada não encontrada não e tratado como erro, a resposta retorna ok com o motivo no warning
+// usado na limpeza de sessões possivelmente inexistentes
+func (c *RequestRtp) DeleteNonFatal() ParametrosOption {
+	return func(s *RequestRtp) error {
+		if s.Command != string(Delete) {
+			return fmt.Errorf("DeleteNonFatal so e aceito no comando %s", Delete)
+		}
+		s.naoFatal = true
+		return nil
+	}
+}
+
+// Prazo da resposta somente deste comando ex: play media longo, os outros comandos continuam com o timeout do client
+func (c *RequestRtp) WithCommandTimeout(d time.Duration) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if d <= 0 {
+			return fmt.Errorf("timeout do comando %s invalido", d)
+		}
+		s.timeout = d
+		return nil
+	}
+}
+
+// Definir o modo do DTMF-security usado no block DTMF: drop, silence, tone, random, zero, DTMF ou off
+func (c *RequestRtp) SetDTMFSecurity(mode string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		for _, o := range dtmfSecurityModes {
+			if o == mode {
+				s.DTMFSecurity = mode
+				return nil
+			}
+		}
+		return fmt.Errorf("DTMF-security %q invalido", mode)
+	}
+}
+
+// Definir a sequencia de digitos que ativa o DTMF-security
+func (c *RequestRtp) SetDTMFSecurityTrigger(digits string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if err := validarDTMF("DTMF-security-trigger", digits); err != nil {
+			return err
+		}
+		s.DTMFSecurityTrigger = digits
+		return nil
+	}
+}
+
+// Definir a sequencia de digitos que desativa o DTMF-security
+func (c *RequestRtp) SetDTMFSecurityTriggerEnd(digits string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if err := validarDTMF("DTMF-security-trigger-end", digits); err != nil {
+			return err
+		}
+		s.DTMFSecurityTriggerEnd = digits
+		return nil
+	}
+}
+
+// Definir a sequencia de digitos DTMF que inicia a ação do trigger ex: DTMF-security ou gravação
+func (c *RequestRtp) SetTrigger(digits string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if err := validarDTMF("trigger", digits); err != nil {
+			return err
+		}
+		s.Trigger = digits
+		return nil
+	}
+}
+
+// Definir a sequencia de digitos DTMF que encerra a ação do trigger
+func (c *RequestRtp) SetTriggerEnd(digits string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if err := validarDTMF("trigger-end", digits); err != nil {
+			return err
+		}
+		s.TriggerEnd = digits
+		return nil
+	}
+}
+
+// Encerrar a ação do trigger apos o tempo em milissegundos, o trigger-end-digits e a outra forma de limitar o trigger
+func (c *RequestRtp) SetTriggerEndTime(ms int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if ms <= 0 {
+			return fmt.Errorf("trigger-end-time %d invalido", ms)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+		s.TriggerEndTime = ms
+		return nil
+	}
+}
+
+// Encerrar a ação do trigger apos a quantidade de digitos recebidos, o trigger-end-time e a outra forma de limitar o trigger
+func (c *RequestRtp) SetTriggerEndDigits(digits int) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if digits <= 0 {
+			return fmt.Errorf("trigger-end-digits %d invalido", digits)
+		}
+
+		if s.ParamsOptInt == nil {
+			s.ParamsOptInt = &ParamsOptInt{}
+		}
+		s.TriggerEndDigits = digits
+		return nil
+	}
+}
+
+// Definir os codigos VSC que o rtpengine compara com o DTMF recebido para iniciar, parar ou pausar a gravação
+func (c *RequestRtp) SetVscCodes(vsc VscConfig) ParametrosOption {
+	return func(s *RequestRtp) error {
+		codigos := []struct {
+			param string
+			valor string
+			campo *string
+		}{
+			{"vsc-start-rec", vsc.StartRec, &s.VscStartRec},
+			{"vsc-stop-rec", vsc.StopRec, &s.VscStopRec},
+			{"vsc-pause-rec", vsc.PauseRec, &s.VscPauseRec},
+			{"vsc-start-stop-rec", vsc.StartStopRec, &s.VscStartStopRec},
+			{"vsc-pause-resume-rec", vsc.PauseResumeRec, &s.VscPauseResumeRec},
+			{"vsc-start-pause-resume-rec", vsc.StartPauseResumeRec, &s.VscStartPauseResumeRec},
+		}
+
+		definidos := 0
+		for _, codigo := range codigos {
+			if codigo.valor == "" {
+				continue
+			}
+			if err := validarDTMF(codigo.param, codigo.valor); err != nil {
+				return err
+			}
+			definidos++
+		}
+
+		if definidos == 0 {
+			return fmt.Errorf("SetVscCodes requer ao menos um codigo")
+		}
+
+		for _, codigo := range codigos {
+			if codigo.valor != "" {
+				*codigo.campo = codigo.valor
+			}
+		}
+		return nil
+	}
+}
+
+// Definir o destino host:porta do log dos eventos DTMF
+func (c *RequestRtp) SetDTMFLogDest(addr string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if err := validarDestino("dtmf-log-dest", addr); err != nil {
+			return err
+		}
+		s.DTMFLogDest = addr
+		return nil
+	}
+}
+
+// Adiciona o replace na lista caso ainda não exista
+func addReplace(s *RequestRtp, replace ParamReplace) {
+	for _, o := range s.Replace {
+		if o == replace {
+			return
+		}
+	}
+	s.Replace = append(s.Replace, replace)
+}
+
+// Familia do endereço ip no formato usado pelo rtpengine
+func addressFamilyIP(ip net.IP) AddressFamily {
+	if ip.To4() != nil {
+		return AddressFamilyIP4
+	}
+	return AddressFamilyIP6
+}
+
+// Valida se o media-address e o received-from usam a mesma familia de endereço
+func validarAddressFamily(request *RequestRtp) error {
+	if request.ParamsOptString == nil || request.ParamsOptStringArray == nil {
+		return nil
+	}
+
+	media := net.ParseIP(request.MediaAddress)
+	if media == nil {
+		return nil
+	}
+
+	for i := 0; i+1 < len(request.ReceivedFrom); i += 2 {
+		family := AddressFamily(request.ReceivedFrom[i])
+		if family != addressFamilyIP(media) {
+			return fmt.Errorf("media-address %s e received-from %s usam familias de endereço diferentes", request.MediaAddress, family)
+		}
+	}
+	return nil
+}
+
+// Prefixo do branch do Via definido na RFC 3261
+const viaBranchMagicCookie = "z9hG4bK"
+
+// Valores aceitos pelo rtpengine no parametro all
+var allValues = []string{"all", "none", "offer-answer", "except-offer-answer", "flows"}
+
+// Valor DSCP das classes, o TOS e o DSCP deslocado em 2 bits
+var dscpClasses = map[string]int{
+	"CS0": 0, "CS1": 8, "CS2": 16, "CS3": 24, "CS4": 32, "CS5": 40, "CS6": 48, "CS7": 56,
+	"AF11": 10, "AF12": 12, "AF13": 14,
+	"AF21": 18, "AF22": 20, "AF23": 22,
+	"AF31": 26, "AF32": 28, "AF33": 30,
+	"AF41": 34, "AF42": 36, "AF43": 38,
+	"VA": 44, "EF": 46,
+}
+
+// Modos aceitos pelo rtpengine no DTMF-security
+var dtmfSecurityModes = []string{"drop", "silence", "tone", "random", "zero", "DTMF", "off"}
+
+// Valida a sequencia de digitos DTMF 0-9, *, # e A-D
+func validarDTMF(param, digits string) error {
+	if digits == "" {
+		return fmt.Errorf("%s requer ao menos um digito DTMF", param)
+	}
+
+	for _, o := range digits {
+		if !strings.ContainsRune("0123456789*#ABCDabcd", o) {
+			return fmt.Errorf("%s com digito DTMF %q invalido", param, o)
+		}
+	}
+	return nil
+}
+
+// Valida o destino no formato host:porta
+func validarDestino(param, destino string) error {
+	host, port, err := net.SplitHostPort(destino)
+	if err != nil {
+		return fmt.Errorf("%s %q invalido: %w", param, destino, err)
+	}
+
+	if numero, err := strconv.Atoi(port); host == "" || err != nil || numero <= 0 || numero > 65535 {
+		return fmt.Errorf("%s %q invalido", param, destino)
+	}
+	return nil
+}
+
+// Valida se a frequencia esta dentro da faixa audivel
+func validarFrequencia(freq int) error {
+	if freq < 20 || freq > 20000 {
+		return fmt.Errorf("frequencia %d Hz fora da faixa audivel de 20 a 20000 Hz", freq)
+	}
+	return nil
+}
+
+// Retorna os parametros da musica em espera garantindo uma unica origem
+func mohOrigem(s *RequestRtp, origem string) (*ParamMoh, error) {
+	if s.Moh == nil {
+		s.Moh = &ParamMoh{}
+	}
+
+	atual := ""
+	switch {
+	case s.Moh.File != "":
+		atual = "file"
+	case s.Moh.Blob != "":
+		atual = "blob"
+	case s.Moh.DbId != "":
+		atual = "db-id"
+	}
+
+	if atual != "" && atual != origem {
+		return nil, fmt.Errorf("moh ja possui a origem %s, não e possivel usar %s", atual, origem)
+	}
+	return s.Moh, nil
+}
+
+// Verifica se o atributo ja possui um substitute na seção
+func sdpAttrSubstituido(comandos *ParamsSdpAttrCommands, attr string) bool {
+	for _, o := range comandos.Substitute {
+		if len(o) > 0 && o[0] == attr {
+			return true
+		}
+	}
+	return false
+}
+
+// Retorna os comandos da seção do sdp-attr criando a estrutura caso não exista
+func sdpAttrSection(s *RequestRtp, section string) (*ParamsSdpAttrCommands, error) {
+	if s.SdpAttr == nil {
+		s.SdpAttr = &ParamsSdpAttrSections{}
+	}
+
+	var comandos **ParamsSdpAttrCommands
+	switch section {
+	case "global":
+		comandos = &s.SdpAttr.Global
+	case "audio":
+		comandos = &s.SdpAttr.Audio
+	case "video":
+		comandos = &s.SdpAttr.Video
+	case "none":
+		comandos = &s.SdpAttr.None
+	default:
+		return nil, fmt.Errorf("sdp-attr seção %q desconhecida", section)
+	}
+
+	if *comandos == nil {
+		*comandos = &ParamsSdpAttrCommands{}
+	}
+	return *comandos, nil
+}
@@ -1,17 +1,24 @@
 package rtpengine
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"strings"
 	"testing"
 	"time"
 
+	bencode "github.com/anacrolix/torrent/bencode"
+	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/require"
 )
 
 func TestClientRequestClientPing(t *testing.T) {
 	t.Run("TestComandoPing", func(t *testing.T) {
-		c := &Engine{}
-		client, err := NewClient(c, WithClientPort(2222), WithClientProto("udp"), WithClientDns("webrtcsrvgcp.callbox.com.br"))
+		transport := newFakeTransport(func(cookie string, comando map[string]interface{}) []byte {
+			return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+		})
+		client, err := NewClient(&Engine{}, WithClientTransport(transport))
 		require.Nil(t, err)
 		require.NotNil(t, client.Engine.con)
 		r := &RequestRtp{
@@ -19,9 +26,11 @@ func TestClientRequestClientPing(t *testing.T) {
 		}
 		response := client.NewComando(r)
 		require.NotNil(t, response)
+		require.Equal(t, ResultPong, response.ResultType())
+		require.Len(t, transport.enviados, 1)
 
 		fmt.Println("Func:", t.Name(), "Comando:"+r.Command, "Resposta:"+response.Result, "Motivo:", response.ErrorReason, client.con.RemoteAddr().String(), "PASS")
-		c.con.Close()
+		client.Close()
 	})
 }
 
@@ -211,3 +220,1229 @@ a=sendrecv`
 //	})
 //
 //}
+
+// SDP usado nos testes dos builders
+const sdpTeste = `v=0
+o=- 1545997027 1 IN IP4 198.51.100.1
+s=tester
+t=0 0
+m=audio 2000 RTP/AVP 0
+c=IN IP4 198.51.100.1
+a=sendrecv`
+
+func TestClientRequestAddressFamily(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Mesma familia", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetMediaAddress("198.51.100.1"),
+			opt.SetReceivedFrom(AddressFamilyIP4, "198.51.100.10"))
+		require.Nil(t, err)
+		require.Equal(t, "198.51.100.1", request.MediaAddress)
+	})
+
+	t.Run("Familias diferentes", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetMediaAddress("2001:db8::1"),
+			opt.SetReceivedFrom(AddressFamilyIP4, "198.51.100.10"))
+		require.NotNil(t, err)
+		require.Nil(t, request)
+	})
+}
+
+func TestClientRequestSetMediaAddress(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("IPv6 com colchetes", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetMediaAddress("[2001:db8::1]"))
+		require.Nil(t, err)
+		require.Equal(t, "2001:db8::1", request.MediaAddress)
+		require.Equal(t, AddressFamilyIP6, request.AddressFamily)
+	})
+
+	t.Run("Familia informada", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste, AddressFamily: AddressFamilyIP6},
+			opt.SetMediaAddress("198.51.100.1"))
+		require.Nil(t, err)
+		require.Equal(t, "198.51.100.1", request.MediaAddress)
+		require.Equal(t, AddressFamilyIP6, request.AddressFamily)
+	})
+
+	t.Run("Hostname", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetMediaAddress("media.example.com"))
+		require.NotNil(t, err)
+		require.Nil(t, request)
+	})
+}
+
+func TestClientRequestSetReceivedFrom(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Par unico", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetReceivedFrom(AddressFamilyIP4, "198.51.100.10"))
+		require.Nil(t, err)
+		require.Equal(t, []string{"IP4", "198.51.100.10"}, request.ReceivedFrom)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "13:received-froml3:IP413:198.51.100.10e")
+	})
+
+	t.Run("Chamado duas vezes", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetReceivedFrom(AddressFamilyIP4, "198.51.100.10"),
+			opt.SetReceivedFrom(AddressFamilyIP4, "198.51.100.11"))
+		require.Nil(t, err)
+		require.Equal(t, []string{"IP4", "198.51.100.11"}, request.ReceivedFrom)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "13:received-froml3:IP413:198.51.100.11e")
+	})
+
+	t.Run("Familia e endereço diferentes", func(t *testing.T) {
+		_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetReceivedFrom(AddressFamilyIP6, "198.51.100.10"))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestToOfferParams(t *testing.T) {
+	hop := 0
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		hop++
+		sdp := strings.ReplaceAll(comando["sdp"].(string), "198.51.100."+fmt.Sprint(hop), "198.51.100."+fmt.Sprint(hop+1))
+		return mockResposta(cookie, map[string]interface{}{"result": "ok", "sdp": sdp})
+	})
+
+	request, err := SDPOffering(&ParamsOptString{FromTag: "asdasdasd494894", CallId: "5464asdas", Sdp: sdpTeste})
+	require.Nil(t, err)
+	primeira := client.NewComando(request)
+	require.NotNil(t, primeira)
+	require.Contains(t, primeira.Sdp, "198.51.100.2")
+
+	parametros := primeira.ToOfferParams()
+	require.Equal(t, "5464asdas", parametros.CallId)
+	require.Equal(t, "asdasdasd494894", parametros.FromTag)
+	require.Equal(t, primeira.Sdp, parametros.Sdp)
+
+	request, err = SDPOffering(parametros)
+	require.Nil(t, err)
+	segunda := client.NewComando(request)
+	require.NotNil(t, segunda)
+	require.Contains(t, segunda.Sdp, "198.51.100.3")
+	require.NotContains(t, segunda.Sdp, "198.51.100.1")
+}
+
+func TestClientRequestSetTransportProtocol(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Valido", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetTransportProtocol(UDP_TLS_RTP_SAVPF))
+		require.Nil(t, err)
+		require.Equal(t, UDP_TLS_RTP_SAVPF, request.TransportProtocol)
+	})
+
+	t.Run("Invalido", func(t *testing.T) {
+		_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetTransportProtocol("RTP/SAPV"))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "RTP/SAPV")
+	})
+
+	t.Run("Vazio", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", TransportProtocol: RTP_AVP, Sdp: sdpTeste}, opt.SetTransportProtocol(""))
+		require.Nil(t, err)
+		require.Equal(t, RTP_AVP, request.TransportProtocol)
+	})
+}
+
+func TestClientRequestSDPObrigatorio(t *testing.T) {
+	t.Run("Offer sem SDP", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+		require.NotNil(t, err)
+		require.Nil(t, request)
+		require.Equal(t, "offer requer um SDP não vazio", err.Error())
+	})
+
+	t.Run("Answer sem parametros", func(t *testing.T) {
+		request, err := SDPAnswer(nil)
+		require.NotNil(t, err)
+		require.Nil(t, request)
+	})
+
+	t.Run("Offer valido", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste})
+		require.Nil(t, err)
+		require.Equal(t, string(Offer), request.Command)
+		require.Equal(t, sdpTeste, request.Sdp)
+	})
+
+	t.Run("Delete sem SDP", func(t *testing.T) {
+		request, err := SDPDelete(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+		require.Nil(t, err)
+		require.Equal(t, string(Delete), request.Command)
+	})
+}
+
+func TestClientRequestT38Gateway(t *testing.T) {
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte { return nil })
+
+	offer, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste})
+	require.Nil(t, err)
+	answer, err := SDPAnswer(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", ToTag: "asdasdad7879", Sdp: sdpTeste})
+	require.Nil(t, err)
+
+	require.Nil(t, client.T38Gateway(offer, answer, false))
+	require.Equal(t, []string{"force"}, offer.T38)
+	require.Empty(t, answer.T38)
+
+	data, err := EncodeComando("cookie", offer)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "4:T.38l5:forcee")
+
+	data, err = EncodeComando("cookie", answer)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "7:command6:answer")
+	require.NotContains(t, string(data), "T.38")
+
+	// o lado T.38 no offer recebe decode
+	reoffer, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste})
+	require.Nil(t, err)
+	reanswer, err := SDPAnswer(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", ToTag: "asdasdad7879", Sdp: sdpTeste})
+	require.Nil(t, err)
+	require.Nil(t, client.T38Gateway(reoffer, reanswer, true))
+
+	data, err = EncodeComando("cookie", reoffer)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "4:T.38l6:decodee")
+	data, err = EncodeComando("cookie", reanswer)
+	require.Nil(t, err)
+	require.NotContains(t, string(data), "T.38")
+
+	require.NotNil(t, client.T38Gateway(answer, offer, true))
+
+	del, err := SDPDelete(&ParamsOptString{CallId: "5464asdas"})
+	require.Nil(t, err)
+	require.NotNil(t, del.SetT38([]T38{T38Stop})(del))
+}
+
+func TestClientRequestCodecOperacoes(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SetCodecOffer([]Codecs{CODEC_OPUS}),
+		opt.SetCodecAccept([]Codecs{CODEC_PCMU, CODEC_PCMA}),
+		opt.SetCodecConsume([]Codecs{CODEC_G729}),
+		opt.SetCodecSet([]string{"opus/48000/2"}))
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{
+		"codec-offer-opus",
+		"codec-accept-PCMU",
+		"codec-accept-PCMA",
+		"codec-consume-G729",
+		"codec-set-opus/48000/2",
+	}, request.Flags)
+}
+
+func TestClientRequestCodecMaskAll(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SetCodecMaskAll(),
+		opt.SetCodecExcept([]Codecs{CODEC_OPUS}))
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{CodecMaskAll, CodecExceptOpus}, request.Flags)
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetCodecStripAll())
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{CodecStripAll}, request.Flags)
+}
+
+func TestClientRequestSetFrequencies(t *testing.T) {
+	opt := &RequestRtp{}
+	request := &RequestRtp{
+		Command:              string(PlayMedia),
+		ParamsOptString:      &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"},
+		ParamsOptStringArray: &ParamsOptStringArray{},
+	}
+
+	require.Nil(t, opt.SetFrequencies([]int{350, 440})(request))
+	require.Equal(t, []string{"350", "440"}, request.Frequencies)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "11:frequenciesl3:3503:440e")
+
+	require.Nil(t, opt.SetFrequency(1000)(request))
+	require.Equal(t, "1000", request.Frequency)
+
+	require.NotNil(t, opt.SetFrequencies([]int{350, -1})(request))
+	require.NotNil(t, opt.SetFrequency(30000)(request))
+}
+func TestClientRequestSetDbId(t *testing.T) {
+	opt := &RequestRtp{}
+	request := &RequestRtp{
+		Command:         string(PlayMedia),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"},
+	}
+
+	require.Nil(t, opt.SetDbId(42)(request))
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "7:command10:play media")
+	require.Contains(t, string(data), "5:db-idi42e")
+
+	require.Nil(t, opt.SetDbId(0)(request))
+	data, err = EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.NotContains(t, string(data), "db-id")
+
+	require.NotNil(t, opt.SetDbId(-1)(request))
+}
+
+func TestClientRequestSetDSCP(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, opt.SetDSCP("EF"))
+	require.Nil(t, err)
+	require.Equal(t, 184, request.TOS)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "3:TOSi184e")
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, opt.SetDSCP("af41"))
+	require.Nil(t, err)
+	require.Equal(t, 136, request.TOS)
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetDSCP("BE2"))
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetTOS(256))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSetSSRC(t *testing.T) {
+	opt := &RequestRtp{}
+	request := &RequestRtp{
+		Command:         string(Offer),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+	}
+
+	require.Nil(t, opt.SetSSRC(3405831957)(request))
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "4:SSRCi3405831957e")
+
+	require.Nil(t, opt.SetSSRC(3405831957, 722931047)(request))
+	data, err = EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "4:SSRCli3405831957ei722931047ee")
+
+	require.NotNil(t, opt.SetSSRC()(request))
+}
+
+func TestClientRequestRepeat(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Repeat times", func(t *testing.T) {
+		request := &RequestRtp{
+			Command:         string(PlayMedia),
+			ParamsOptString: &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", File: "/var/media/anuncio.wav"},
+		}
+		require.Nil(t, opt.SetStartPos(1500)(request))
+		require.Nil(t, opt.SetRepeatTimes(3)(request))
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "9:start-posi1500e")
+		require.Contains(t, string(data), "12:repeat-timesi3e")
+		require.NotContains(t, string(data), "rstart-pos")
+		require.NotContains(t, string(data), "repeat-duration")
+
+		require.NotNil(t, opt.SetRepeatDuration(10000)(request))
+	})
+
+	t.Run("Repeat duration", func(t *testing.T) {
+		request := &RequestRtp{Command: string(PlayMedia), ParamsOptString: &ParamsOptString{CallId: "5464asdas"}}
+		require.Nil(t, opt.SetRepeatDuration(10000)(request))
+		require.NotNil(t, opt.SetRepeatTimes(2)(request))
+		require.Equal(t, 0, request.RepeatTimes)
+		require.NotNil(t, opt.SetStartPos(-1)(request))
+	})
+}
+
+func TestClientRequestBehaviorFlags(t *testing.T) {
+	opt := &RequestRtp{}
+	flags := map[ParamFlags]ParametrosOption{
+		EarlyMedia:     opt.EarlyMedia(),
+		Passthrough:    opt.Passthrough(),
+		NoPassthrough:  opt.NoPassthrough(),
+		NoJitterBuffer: opt.NoJitterBuffer(),
+	}
+	for flag, option := range flags {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, option)
+		require.Nil(t, err)
+		require.Equal(t, []ParamFlags{flag}, request.Flags)
+	}
+
+	_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.Passthrough(), opt.NoPassthrough())
+	require.NotNil(t, err)
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.NoPassthrough(), opt.Passthrough())
+	require.NotNil(t, err)
+}
+
+func TestClientRequestMediaHandover(t *testing.T) {
+	opt := &RequestRtp{}
+	offer, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste})
+	require.Nil(t, err)
+	require.False(t, offer.HasFlag(MediaHandover))
+
+	reoffer, err := SDPOffering(&ParamsOptString{CallId: offer.CallId, FromTag: offer.FromTag, Sdp: strings.ReplaceAll(sdpTeste, "198.51.100.1", "203.0.113.7")},
+		opt.MediaHandover(),
+		opt.SetMediaAddress("203.0.113.7"))
+	require.Nil(t, err)
+	require.True(t, reoffer.HasFlag(MediaHandover))
+	require.Equal(t, "203.0.113.7", reoffer.MediaAddress)
+
+	data, err := EncodeComando("cookie", reoffer)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "5:flagsl14:media-handovere")
+	require.Contains(t, string(data), "13:media-address11:203.0.113.7")
+}
+
+func TestClientRequestJitterBuffer(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, opt.JitterBuffer(5))
+	require.Nil(t, err)
+	require.Equal(t, 5, request.DelayBuffer)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "12:delay-bufferi5e")
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, opt.DisableJitterBuffer())
+	require.Nil(t, err)
+	require.True(t, request.HasFlag(NoJitterBuffer))
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.JitterBuffer(5), opt.DisableJitterBuffer())
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.DisableJitterBuffer(), opt.JitterBuffer(5))
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.JitterBuffer(0))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestGenerateMid(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := ProfilerSIPToWebRTC_Offer(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.GenerateMid(),
+		opt.StripExtmap())
+	require.Nil(t, err)
+	require.True(t, request.HasFlag(GenerateMid))
+	require.True(t, request.HasFlag(StripExtmap))
+	require.False(t, request.HasFlag(Fragment))
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "12:generate-mid")
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, opt.Fragment())
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{Fragment}, request.Flags)
+}
+
+func TestClientRequestRTCPAttribute(t *testing.T) {
+	opt := &RequestRtp{}
+	flags := map[ParamFlags]ParametrosOption{
+		StripExtmap:       opt.StripExtmap(),
+		NoRtcpAttribute:   opt.NoRTCPAttribute(),
+		FullRtcpAttribute: opt.FullRTCPAttribute(),
+	}
+	for flag, option := range flags {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, option)
+		require.Nil(t, err)
+		require.Equal(t, []ParamFlags{flag}, request.Flags)
+	}
+
+	_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.NoRTCPAttribute(), opt.FullRTCPAttribute())
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.FullRTCPAttribute(), opt.NoRTCPAttribute())
+	require.NotNil(t, err)
+}
+
+func TestClientRequestCodecPolicy(t *testing.T) {
+	opt := &RequestRtp{}
+	flags := map[ParamFlags]ParametrosOption{
+		SingleCodec:          opt.SingleCodec(),
+		StaticCodecs:         opt.StaticCodecs(),
+		ReuseCodecs:          opt.ReuseCodecs(),
+		NoCodecRenegotiation: opt.NoCodecRenegotiation(),
+	}
+	for flag, option := range flags {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, option)
+		require.Nil(t, err)
+		require.Equal(t, []ParamFlags{flag}, request.Flags)
+	}
+
+	request, err := SDPAnswer(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", ToTag: "asdasdad7879000", Sdp: sdpTeste},
+		opt.SingleCodec(),
+		opt.NoCodecRenegotiation())
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{SingleCodec, NoCodecRenegotiation}, request.Flags)
+}
+
+func TestClientRequestDirectionFlags(t *testing.T) {
+	opt := &RequestRtp{}
+	flags := map[ParamFlags]ParametrosOption{
+		Unidirectional: opt.Unidirectional(),
+		Egress:         opt.Egress(),
+		BlockEgress:    opt.BlockEgress(),
+	}
+	for flag, option := range flags {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, option)
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), fmt.Sprintf("5:flagsl%d:%se", len(flag), flag))
+	}
+
+	request, err := SDPSilenceMedia(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"}, opt.Egress())
+	require.Nil(t, err)
+	require.True(t, request.HasFlag(Egress))
+}
+
+func TestClientRequestAddRemoveFlag(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SetFlags([]ParamFlags{TrustAddress, Unidirectional}),
+		opt.AddFlag(GenerateMid),
+		opt.AddFlag(GenerateMid),
+		opt.AddFlag(Unidirectional))
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{TrustAddress, Unidirectional, GenerateMid}, request.Flags)
+	require.True(t, request.HasFlag(Unidirectional))
+
+	request.Flags = append(request.Flags, Unidirectional)
+	require.Nil(t, opt.RemoveFlag(Unidirectional)(request))
+	require.False(t, request.HasFlag(Unidirectional))
+	require.Nil(t, opt.RemoveFlag(Unidirectional)(request))
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "5:flagsl13:trust-address12:generate-mide")
+	require.NotContains(t, string(data), "unidirectional")
+
+	require.False(t, (&RequestRtp{}).HasFlag(TrustAddress))
+}
+
+func TestClientRequestStripICEKeepDTLS(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste, DTLS: DTLSPassive, ICE: ICEForce},
+		opt.StripICEKeepDTLS())
+	require.Nil(t, err)
+	require.Equal(t, ICERemove, request.ICE)
+	require.Equal(t, DTLSPassive, request.DTLS)
+	require.Empty(t, request.Replace)
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SetReplace([]ParamReplace{SessionName}),
+		opt.StripICEKeepDTLS())
+	require.Nil(t, err)
+	require.Equal(t, []ParamReplace{SessionName}, request.Replace)
+}
+
+func TestClientRequestReplace(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("SessionConnection depreciado", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetReplace([]ParamReplace{Origin, SessionConnection}))
+		require.Nil(t, err)
+		require.Equal(t, []ParamReplace{Origin}, request.Replace)
+	})
+
+	t.Run("Helpers", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.ReplaceOrigin(),
+			opt.ReplaceSessionName(),
+			opt.ReplaceUsername(),
+			opt.ReplaceZeroAddress(),
+			opt.ForceIncrementSDPVersion(),
+			opt.ReplaceOrigin())
+		require.Nil(t, err)
+		require.Equal(t, []ParamReplace{Origin, SessionName, Username, ZeroAddress, ForceIncrementSdpVersion}, request.Replace)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "7:replacel6:origin12:session-name8:username12:zero-address27:force-increment-sdp-versione")
+	})
+	t.Run("Alias force-increment-sdp-ver", func(t *testing.T) {
+		alias, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetReplace([]ParamReplace{ForceIncrementSdpVer}))
+		require.Nil(t, err)
+
+		canonico, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.ForceIncrementSDPVersion())
+		require.Nil(t, err)
+		require.Equal(t, canonico.Replace, alias.Replace)
+
+		data, err := EncodeComando("cookie", alias)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "7:replacel27:force-increment-sdp-versione")
+
+		ambos, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetReplace([]ParamReplace{ForceIncrementSdpVersion, ForceIncrementSdpVer}))
+		require.Nil(t, err)
+		require.Equal(t, []ParamReplace{ForceIncrementSdpVersion}, ambos.Replace)
+	})
+}
+
+func TestClientRequestMoh(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Blob", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetMohBlob("RIFF"),
+			opt.SetMohMode("sendrecv"))
+		require.Nil(t, err)
+		require.Equal(t, &ParamMoh{Blob: "RIFF", Mode: "sendrecv"}, request.Moh)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "3:mohd4:blob4:RIFF4:mode8:sendrecve")
+	})
+
+	t.Run("Conexão zero", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetMohFile("/var/lib/moh.wav"),
+			opt.SetMohConnection(MohConnection))
+		require.Nil(t, err)
+		require.Equal(t, &ParamMoh{File: "/var/lib/moh.wav", Mode: "sendonly", Connection: MohConnection}, request.Moh)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "10:connection4:zero")
+	})
+
+	t.Run("Origem unica", func(t *testing.T) {
+		_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetMohFile("/var/lib/moh.wav"),
+			opt.SetMohDbId("12"))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestSdpAttr(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SubstituteSdpAttr("audio", "a=sendrecv", "a=sendonly"),
+		opt.RemoveSdpAttr("global", "ice-lite"))
+	require.Nil(t, err)
+	require.Equal(t, [][]string{{"a=sendrecv", "a=sendonly"}}, request.SdpAttr.Audio.Substitute)
+	require.Equal(t, []string{"ice-lite"}, request.SdpAttr.Global.Remove)
+	require.Nil(t, request.SdpAttr.Video)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "8:sdp-attrd5:audiod10:substitutell10:a=sendrecv10:a=sendonlyeee6:globald6:removel8:ice-liteeee")
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.AddSdpAttr("imagem", "a=x"))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSetOutputDestination(t *testing.T) {
+	opt := &RequestRtp{}
+	request := &RequestRtp{
+		Command:         string(StartRecording),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"},
+	}
+
+	require.Nil(t, opt.SetOutputDestination("/var/spool/rtpengine/5464asdas")(request))
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "7:command15:start recording")
+	require.Contains(t, string(data), "18:output-destination30:/var/spool/rtpengine/5464asdas")
+
+	require.NotNil(t, opt.SetOutputDestination(" ")(request))
+}
+
+func TestClientRequestSetXmlrpcCallback(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("URL valida", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetXmlrpcCallback("https://kamailio.example.com:8443/RPC2"))
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "15:xmlrpc-callback38:https://kamailio.example.com:8443/RPC2")
+	})
+
+	t.Run("FTP rejeitado", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetXmlrpcCallback("ftp://kamailio.example.com/RPC2"))
+		require.NotNil(t, err)
+		require.Nil(t, request)
+		require.Contains(t, err.Error(), "http ou https")
+	})
+}
+
+func TestClientRequestSetOpusCodec(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.SetOpusCodec(OpusOptions{Channels: 1, Bitrate: 16000, MaxPlaybackRate: 16000, CBR: true}))
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{"codec-set-opus/48000/1/16000/maxplaybackrate=16000;cbr=1"}, request.Flags)
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.SetOpusCodec(OpusOptions{}))
+	require.Nil(t, err)
+	require.Equal(t, []ParamFlags{"codec-set-opus/48000/2"}, request.Flags)
+
+	for _, opus := range []OpusOptions{{Channels: 3}, {Bitrate: 5000}, {Bitrate: 600000}, {MaxPlaybackRate: 96000}} {
+		_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetOpusCodec(opus))
+		require.NotNil(t, err)
+	}
+}
+
+func TestClientRequestSetICE(t *testing.T) {
+	opt := &RequestRtp{}
+	for _, mode := range []ICE{ICERemove, ICEForce, ICEDefault, ICEForceRelay, ICEOptional} {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, opt.SetICE(mode))
+		require.Nil(t, err)
+		require.Equal(t, mode, request.ICE)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), fmt.Sprintf("3:ICE%d:%s", len(mode), mode))
+	}
+
+	_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste}, opt.SetICE("relay-only"))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSetTemplate(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SetTemplate("WebRTC"),
+		opt.SetFlags([]ParamFlags{TrustAddress}))
+	require.Nil(t, err)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "8:template6:WebRTC")
+	require.Contains(t, string(data), "5:flagsl13:trust-addresse")
+
+	require.NotNil(t, opt.SetTemplate("")(request))
+}
+
+func TestClientRequestSetPtimeSymmetric(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetPtimeSymmetric(20))
+	require.Nil(t, err)
+	require.Equal(t, 20, request.Ptime)
+	require.Equal(t, 20, request.PtimeReverse)
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetPtimeSymmetric(5))
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetPtimeSymmetric(120))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSDPDeleteDelay(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Delete imediato", func(t *testing.T) {
+		request, err := SDPDelete(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"}, opt.SetDeleteDelay(0))
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "12:delete-delayi0e")
+
+		request, err = SDPDelete(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+		require.Nil(t, err)
+		data, err = EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.NotContains(t, string(data), "delete-delay")
+	})
+
+	t.Run("Chamada não encontrada", func(t *testing.T) {
+		client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+			return []byte(cookie + " d12:error-reason15:Unknown call-id6:result5:errore")
+		})
+
+		request, err := SDPDelete(&ParamsOptString{CallId: "5464asdas"}, opt.SetDeleteDelay(0))
+		require.Nil(t, err)
+		response := client.NewComando(request)
+		require.Equal(t, "error", response.Result)
+		require.False(t, response.CallFound())
+
+		request, err = SDPDelete(&ParamsOptString{CallId: "5464asdas"}, opt.SetDeleteDelay(0), opt.DeleteNonFatal())
+		require.Nil(t, err)
+		response = client.NewComando(request)
+		require.Equal(t, "ok", response.Result)
+		require.Equal(t, "Unknown call-id", response.Warning)
+		require.False(t, response.CallFound())
+	})
+
+	t.Run("Chamada encontrada", func(t *testing.T) {
+		require.True(t, (&ResponseRtp{Result: "ok"}).CallFound())
+	})
+}
+
+func TestClientRequestSetTrigger(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.SetTrigger("*9"),
+		opt.SetTriggerEndDigits(4))
+	require.Nil(t, err)
+	require.Equal(t, "*9", request.Trigger)
+	require.Equal(t, 4, request.TriggerEndDigits)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "7:trigger2:*9")
+	require.Contains(t, string(data), "18:trigger-end-digitsi4e")
+	require.NotContains(t, string(data), "trigger-end-time")
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.SetTrigger("*9"),
+		opt.SetTriggerEnd("#"),
+		opt.SetTriggerEndTime(5000))
+	require.Nil(t, err)
+	require.Equal(t, "#", request.TriggerEnd)
+	require.Equal(t, 5000, request.TriggerEndTime)
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetTrigger("9x"))
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetTriggerEnd(""))
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetTriggerEndDigits(0))
+	require.NotNil(t, err)
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetTriggerEndTime(-1))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSetVscCodes(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.SetVscCodes(VscConfig{StartRec: "*97", StopRec: "*98", PauseRec: "*99", StartStopRec: "*96", PauseResumeRec: "*95", StartPauseResumeRec: "#94"}))
+	require.Nil(t, err)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "13:vsc-start-rec3:*97")
+	require.Contains(t, string(data), "12:vsc-stop-rec3:*98")
+	require.Contains(t, string(data), "13:vsc-pause-rec3:*99")
+	require.Contains(t, string(data), "18:vsc-start-stop-rec3:*96")
+	require.Contains(t, string(data), "20:vsc-pause-resume-rec3:*95")
+	require.Contains(t, string(data), "26:vsc-start-pause-resume-rec3:#94")
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894", Sdp: sdpTeste},
+		opt.SetVscCodes(VscConfig{StartRec: "*97", StopRec: "*98"}))
+	require.Nil(t, err)
+	data, err = EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.NotContains(t, string(data), "vsc-pause-rec")
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetVscCodes(VscConfig{StartRec: "*9x"}))
+	require.NotNil(t, err)
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetVscCodes(VscConfig{}))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestDTMFSecurity(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Silence no trigger", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetDTMFSecurity("silence"),
+			opt.SetDTMFSecurityTrigger("*9"),
+			opt.SetDTMFSecurityTriggerEnd("#"),
+			opt.SetDTMFLogDest("198.51.100.30:9000"))
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "13:DTMF-security7:silence")
+		require.Contains(t, string(data), "21:DTMF-security-trigger2:*9")
+		require.Contains(t, string(data), "25:DTMF-security-trigger-end1:#")
+		require.Contains(t, string(data), "13:dtmf-log-dest18:198.51.100.30:9000")
+	})
+
+	t.Run("Validação", func(t *testing.T) {
+		request := &RequestRtp{ParamsOptString: &ParamsOptString{}}
+		require.NotNil(t, opt.SetDTMFSecurity("mute")(request))
+		require.NotNil(t, opt.SetDTMFSecurity("")(request))
+		require.Nil(t, opt.SetDTMFSecurity("DTMF")(request))
+		require.NotNil(t, opt.SetDTMFSecurityTrigger("12x")(request))
+		require.NotNil(t, opt.SetDTMFSecurityTriggerEnd("")(request))
+		require.NotNil(t, opt.SetDTMFLogDest("198.51.100.30")(request))
+	})
+}
+
+func TestClientRequestProfilerWebRTC(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("WebRTC para SIP", func(t *testing.T) {
+		request, err := ProfilerWebRTCToSIP_Offer(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste})
+		require.Nil(t, err)
+		require.Equal(t, RTP_AVP, request.TransportProtocol)
+		require.Equal(t, ICERemove, request.ICE)
+		require.Equal(t, DTLSOff, request.DTLS)
+		require.Equal(t, []SDES{SDESOff}, request.SDES)
+		require.Equal(t, []ParamFlags{CodecMaskOpus, "codec-transcode-PCMU"}, request.Flags)
+	})
+
+	t.Run("SIP para WebRTC", func(t *testing.T) {
+		request, err := ProfilerSIPToWebRTC_Offer(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.AddFlag(TrickleICE))
+		require.Nil(t, err)
+		require.Equal(t, UDP_TLS_RTP_SAVPF, request.TransportProtocol)
+		require.Equal(t, ICEForce, request.ICE)
+		require.Equal(t, DTLSPassive, request.DTLS)
+		require.Equal(t, []ParamFlags{CodecTranscodeOpus, TrickleICE}, request.Flags)
+	})
+
+	t.Run("Sem SDP", func(t *testing.T) {
+		_, err := ProfilerSIPToWebRTC_Offer(&ParamsOptString{CallId: "5464asdas"})
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestProfilerSIPREC(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := ProfilerSIPREC_Offer(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, "uuid=5464asdas;agent=kamailio",
+		opt.SetOutputDestination("/var/spool/rtpengine/siprec"))
+	require.Nil(t, err)
+	require.True(t, request.HasFlag(SIPREC))
+	require.True(t, request.HasFlag(RecordCall))
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "8:metadata29:uuid=5464asdas;agent=kamailio")
+	require.Contains(t, string(data), "5:flagsl6:SIPREC11:record-calle")
+
+	_, err = ProfilerSIPREC_Offer(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, "")
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSetViaBranchTag(t *testing.T) {
+	opt := &RequestRtp{}
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "ok"})
+	})
+	buf := &bytes.Buffer{}
+	client.log = zerolog.New(buf).Level(zerolog.WarnLevel)
+
+	t.Run("Branch valido", func(t *testing.T) {
+		buf.Reset()
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetViaBranchTag("z9hG4bK776asdhds"))
+		require.Nil(t, err)
+		require.Equal(t, "z9hG4bK776asdhds", request.ViaBranch)
+		require.NotNil(t, client.NewComando(request))
+		require.Empty(t, buf.String())
+	})
+
+	t.Run("Branch legado", func(t *testing.T) {
+		buf.Reset()
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetViaBranchTag("776asdhds"))
+		require.Nil(t, err)
+		require.Equal(t, "776asdhds", request.ViaBranch)
+		require.NotNil(t, client.NewComando(request))
+		require.Contains(t, buf.String(), `"level":"warn"`)
+		require.Contains(t, buf.String(), "776asdhds")
+	})
+
+	t.Run("Branch vazio", func(t *testing.T) {
+		_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetViaBranchTag(""))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestLoopProtect(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Ativado", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.LoopProtect(true))
+		require.Nil(t, err)
+		require.True(t, request.HasFlag(LoopProtect))
+	})
+
+	t.Run("Desativado no profile", func(t *testing.T) {
+		request, err := ProfilerSIPToWebRTC_Offer(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.LoopProtect(true), opt.LoopProtect(false))
+		require.Nil(t, err)
+		require.False(t, request.HasFlag(LoopProtect))
+	})
+
+	t.Run("Com media-echo", func(t *testing.T) {
+		_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste, MediaEcho: "forward"}, opt.LoopProtect(true))
+		require.NotNil(t, err)
+
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste, MediaEcho: "forward"}, opt.LoopProtect(false))
+		require.Nil(t, err)
+		require.False(t, request.HasFlag(LoopProtect))
+	})
+}
+
+func TestClientRequestSetFromTags(t *testing.T) {
+	opt := &RequestRtp{}
+	request := &RequestRtp{
+		Command:         string(SubscribeRequest),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas"},
+	}
+
+	require.Nil(t, opt.SetFromTags([]string{"perna1", "perna2"})(request))
+	require.Nil(t, opt.SetFromTags([]string{"perna3"})(request))
+	require.Equal(t, []string{"perna1", "perna2", "perna3"}, request.FromTags)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "7:command17:subscribe request")
+	require.Contains(t, string(data), "9:from-tagsl6:perna16:perna26:perna3e")
+
+	require.NotNil(t, opt.SetFromTags([]string{""})(request))
+}
+
+func TestClientRequestSetInterface(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Interface unica", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetInterface("external"))
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "9:interface8:external")
+	})
+
+	t.Run("Par de interfaces", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetInterfacePair("internal", "external"))
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "9:interfacel8:internal8:externale")
+	})
+
+	t.Run("Sem interface", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste})
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.NotContains(t, string(data), "interface")
+		require.NotNil(t, opt.SetInterfacePair("internal", "")(request))
+	})
+}
+
+func TestClientRequestSetDirection(t *testing.T) {
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetDirection("private", "public"))
+	require.Nil(t, err)
+	require.Equal(t, []string{"private", "public"}, request.Direction)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "9:directionl7:private6:publice")
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetDirection("private", ""))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSetAll(t *testing.T) {
+	opt := &RequestRtp{}
+	request := &RequestRtp{
+		Command:         string(BlockMedia),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas"},
+	}
+
+	require.Nil(t, opt.SetAll("all")(request))
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "3:all3:all")
+	require.Contains(t, string(data), "7:command11:block media")
+
+	require.NotNil(t, opt.SetAll("todos")(request))
+	require.Equal(t, "all", request.All)
+}
+
+func TestClientRequestSDPSilenceMedia(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Perna", func(t *testing.T) {
+		request, err := SDPSilenceMedia(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "7:command13:silence media")
+		require.Contains(t, string(data), "8:from-tag15:asdasdasd494894")
+		require.NotContains(t, string(data), "3:all")
+	})
+
+	t.Run("Chamada inteira", func(t *testing.T) {
+		request, err := SDPUnsilenceMedia(&ParamsOptString{CallId: "5464asdas"}, opt.SetAll("all"))
+		require.Nil(t, err)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "7:command15:unsilence media")
+		require.Contains(t, string(data), "3:all3:all")
+	})
+
+	t.Run("Sem escopo", func(t *testing.T) {
+		_, err := SDPSilenceMedia(&ParamsOptString{CallId: "5464asdas"})
+		require.NotNil(t, err)
+
+		_, err = SDPSilenceMedia(nil)
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestSDPForwarding(t *testing.T) {
+	opt := &RequestRtp{}
+
+	start, err := SDPStartForwarding(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"},
+		opt.SetOutputDestination("198.51.100.40:7000"))
+	require.Nil(t, err)
+
+	data, err := EncodeComando("cookie", start)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "7:command16:start forwarding")
+	require.Contains(t, string(data), "8:from-tag15:asdasdasd494894")
+	require.Contains(t, string(data), "18:output-destination18:198.51.100.40:7000")
+
+	stop, err := SDPStopForwarding(&ParamsOptString{CallId: start.CallId, FromTag: start.FromTag})
+	require.Nil(t, err)
+	data, err = EncodeComando("cookie", stop)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "7:command15:stop forwarding")
+	require.Contains(t, string(data), "8:from-tag15:asdasdasd494894")
+
+	_, err = SDPStartForwarding(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+	require.NotNil(t, err)
+
+	_, err = SDPStopForwarding(&ParamsOptString{FromTag: "asdasdasd494894"})
+	require.NotNil(t, err)
+}
+
+func TestClientRequestSetBlob(t *testing.T) {
+	opt := &RequestRtp{}
+	blob := []byte{'R', 'I', 'F', 'F', 0x00, 0x00, 0xff, 0xfe, 0x80, 0x7f, 0x00, ' ', 'e', ':'}
+	request := &RequestRtp{
+		Command:         string(PlayMedia),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"},
+	}
+	require.Nil(t, opt.SetBlob(blob)(request))
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+
+	comando := make(map[string]interface{})
+	require.Nil(t, bencode.Unmarshal(data[len("cookie "):], &comando))
+	require.Equal(t, blob, []byte(comando["blob"].(string)))
+
+	require.NotNil(t, opt.SetBlob(nil)(request))
+}
+
+func TestClientRequestSetSdpAttrSubstituteRegex(t *testing.T) {
+	opt := &RequestRtp{}
+	sdp := sdpTeste + "\na=rtpmap:0 PCMU/8000\na=rtpmap:101 telephone-event/8000\nm=video 2002 RTP/AVP 96\na=rtpmap:96 VP8/90000"
+
+	t.Run("Expressão valida", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdp},
+			opt.SetSdpAttrSubstituteRegex("audio", `^rtpmap:(\d+) (.+)/8000$`, "rtpmap:$1 $2/16000"))
+		require.Nil(t, err)
+		require.Equal(t, [][]string{
+			{"rtpmap:0 PCMU/8000", "rtpmap:0 PCMU/16000"},
+			{"rtpmap:101 telephone-event/8000", "rtpmap:101 telephone-event/16000"},
+		}, request.SdpAttr.Audio.Substitute)
+		require.Nil(t, request.SdpAttr.Video)
+
+		data, err := EncodeComando("cookie", request)
+		require.Nil(t, err)
+		require.Contains(t, string(data), "10:substitutell18:rtpmap:0 PCMU/800019:rtpmap:0 PCMU/16000e")
+	})
+
+	t.Run("Expressão invalida", func(t *testing.T) {
+		_, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdp},
+			opt.SetSdpAttrSubstituteRegex("audio", `rtpmap:(\d+`, "x"))
+		require.NotNil(t, err)
+
+		_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdp},
+			opt.SetSdpAttrSubstituteRegex("none", `rtpmap`, "x"))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestSetReceivedFromIP(t *testing.T) {
+	opt := &RequestRtp{}
+
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SetReceivedFromIP(net.IPv4(198, 51, 100, 10)),
+		opt.SetReceivedFromIP(net.IP{198, 51, 100, 11}))
+	require.Nil(t, err)
+	require.Equal(t, []string{"IP4", "198.51.100.11"}, request.ReceivedFrom)
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "13:received-froml3:IP413:198.51.100.11e")
+
+	request, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+		opt.SetReceivedFromIP(net.IPv4(198, 51, 100, 10)),
+		opt.SetReceivedFromIP(net.ParseIP("2001:db8::10")))
+	require.Nil(t, err)
+	require.Equal(t, []string{"IP6", "2001:db8::10"}, request.ReceivedFrom)
+
+	data, err = EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "13:received-froml3:IP612:2001:db8::10e")
+
+	_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.SetReceivedFromIP(nil))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestNATMode(t *testing.T) {
+	opt := &RequestRtp{}
+
+	t.Run("Helpers", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetFlags([]ParamFlags{TrustAddress}),
+			opt.TrustAddress(),
+			opt.Symmetric(),
+			opt.StrictSource(),
+			opt.PierceNAT(),
+			opt.Asymmetric())
+		require.Nil(t, err)
+		require.Equal(t, []ParamFlags{TrustAddress, Symmetric, StrictSource, PierceNAT, Asymmetric}, request.Flags)
+	})
+
+	t.Run("NATMode", func(t *testing.T) {
+		modos := map[NATMode][]ParamFlags{
+			NATBehind:  {Symmetric, PierceNAT},
+			NATTrusted: {TrustAddress, Asymmetric},
+			NATStrict:  {Symmetric, StrictSource},
+		}
+		for modo, flags := range modos {
+			request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.NATMode(modo))
+			require.Nil(t, err)
+			require.Equal(t, flags, request.Flags)
+		}
+
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste},
+			opt.SetFlags([]ParamFlags{Symmetric}), opt.NATMode(NATBehind), opt.Symmetric())
+		require.Nil(t, err)
+		require.Equal(t, []ParamFlags{Symmetric, PierceNAT}, request.Flags)
+
+		_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas", Sdp: sdpTeste}, opt.NATMode("full-cone"))
+		require.NotNil(t, err)
+	})
+}
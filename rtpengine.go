@@ -2,20 +2,28 @@ package rtpengine
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
 
 	bencode "github.com/anacrolix/torrent/bencode"
 	"github.com/google/uuid"
 )
 
 type Engine struct {
-	con   net.Conn
-	ip    net.IP
-	port  int
-	dns   *net.Resolver
-	proto string
-	ng    int
+	con            net.Conn
+	ip             net.IP
+	port           int
+	dns            *net.Resolver
+	proto          string
+	ng             int
+	connectTimeout time.Duration
+	ipAlternativo  net.IP
 }
 
 // Estrutura da requisicão do comando
@@ -24,25 +32,34 @@ type RequestRtp struct {
 	*ParamsOptString
 	*ParamsOptInt
 	*ParamsOptStringArray
+	naoFatal bool
+	timeout  time.Duration
+	// delete-delay zero definido no SetDeleteDelay, o omitempty removeria o valor
+	deleteImediato bool
 }
 
 // Estrutura da resposta do comando
 type ResponseRtp struct {
-	Result      string      `json:"result" bencode:"result"`
-	Sdp         string      `json:"sdp,omitempty" bencode:"sdp,omitempty"`
-	ErrorReason string      `json:"error-reason,omitempty" bencode:"error-reason,omitempty"`
-	Warning     string      `json:"warning,omitempty" bencode:"warning,omitempty"`
-	Created     int         `json:"created,omitempty" bencode:"created,omitempty"`
-	CreatedUs   int         `json:"created_us,omitempty" bencode:"created_us,omitempty"`
-	LastSignal  int         `json:"last signal,omitempty" bencode:"last signal,omitempty"`
-	SSRC        interface{} `json:"SSRC,omitempty" bencode:"SSRC,omitempty"`
-	Tags        interface{} `json:"tags,omitempty" bencode:"tags,omitempty"`
-	Totals      TotalRTP    `json:"totals,omitempty" bencode:"totals,omitempty"`
+	Result          string                 `json:"result" bencode:"result"`
+	Sdp             string                 `json:"sdp,omitempty" bencode:"sdp,omitempty"`
+	ErrorReason     string                 `json:"error-reason,omitempty" bencode:"error-reason,omitempty"`
+	Warning         string                 `json:"warning,omitempty" bencode:"warning,omitempty"`
+	Created         int                    `json:"created,omitempty" bencode:"created,omitempty"`
+	CreatedUs       int                    `json:"created_us,omitempty" bencode:"created_us,omitempty"`
+	LastSignal      int                    `json:"last signal,omitempty" bencode:"last signal,omitempty"`
+	LastRedisUpdate int                    `json:"last redis update,omitempty" bencode:"last redis update,omitempty"`
+	SSRC            interface{}            `json:"SSRC,omitempty" bencode:"SSRC,omitempty"`
+	Tags            map[string]QueryTag    `json:"tags,omitempty" bencode:"tags,omitempty"`
+	Calls           []string               `json:"calls,omitempty" bencode:"calls,omitempty"`
+	Totals          TotalRTP               `json:"totals,omitempty" bencode:"totals,omitempty"`
+	Extra           map[string]interface{} `json:"extra,omitempty" bencode:"-"`
+	comando         *RequestRtp
+	raw             []byte
 }
 
 type TotalRTP struct {
 	Rtp  ValuesRTP `json:"RTP,omitempty" bencode:"RTP,omitempty"`
-	Rtcp ValuesRTP `json:"RCTP,omitempty" bencode:"RTP,omitempty"`
+	Rtcp ValuesRTP `json:"RTCP,omitempty" bencode:"RTCP,omitempty"`
 }
 type ValuesRTP struct {
 	Packets int `json:"packets,omitempty" bencode:"packets,omitempty"`
@@ -50,6 +67,47 @@ type ValuesRTP struct {
 	Errors  int `json:"errors,omitempty" bencode:"errors,omitempty"`
 }
 
+// Estrutura da resposta do comando query
+type QueryResponse struct {
+	*ResponseRtp
+	CallId string `json:"call-id"`
+}
+
+// Detalhes de cada tag (perna) da chamada retornados pelo query
+type QueryTag struct {
+	Tag            string       `json:"tag,omitempty" bencode:"tag,omitempty"`
+	Label          string       `json:"label,omitempty" bencode:"label,omitempty"`
+	Created        int          `json:"created,omitempty" bencode:"created,omitempty"`
+	InDialogueWith string       `json:"in dialogue with,omitempty" bencode:"in dialogue with,omitempty"`
+	Medias         []QueryMedia `json:"medias,omitempty" bencode:"medias,omitempty"`
+}
+
+// Detalhes de cada midia da tag
+type QueryMedia struct {
+	Index    int           `json:"index,omitempty" bencode:"index,omitempty"`
+	Type     string        `json:"type,omitempty" bencode:"type,omitempty"`
+	Protocol string        `json:"protocol,omitempty" bencode:"protocol,omitempty"`
+	Flags    []string      `json:"flags,omitempty" bencode:"flags,omitempty"`
+	Streams  []QueryStream `json:"streams,omitempty" bencode:"streams,omitempty"`
+}
+
+// Detalhes de cada stream da midia
+type QueryStream struct {
+	LocalPort          int           `json:"local port,omitempty" bencode:"local port,omitempty"`
+	Endpoint           QueryEndpoint `json:"endpoint,omitempty" bencode:"endpoint,omitempty"`
+	AdvertisedEndpoint QueryEndpoint `json:"advertised endpoint,omitempty" bencode:"advertised endpoint,omitempty"`
+	LastPacket         int           `json:"last packet,omitempty" bencode:"last packet,omitempty"`
+	Flags              []string      `json:"flags,omitempty" bencode:"flags,omitempty"`
+	Stats              ValuesRTP     `json:"stats,omitempty" bencode:"stats,omitempty"`
+}
+
+// Endereço do stream
+type QueryEndpoint struct {
+	Family  string `json:"family,omitempty" bencode:"family,omitempty"`
+	Address string `json:"address,omitempty" bencode:"address,omitempty"`
+	Port    int    `json:"port,omitempty" bencode:"port,omitempty"`
+}
+
 // Parametros de comportamento
 type ParamsOptString struct {
 	FromTag                string                 `json:"from-tag" bencode:"from-tag"`
@@ -92,22 +150,29 @@ type ParamsOptString struct {
 	VscPauseResumeRec      string                 `json:"vsc-pause-resume-rec,omitempty" bencode:"vsc-pause-resume-rec,omitempty"`
 	VscStartPauseResumeRec string                 `json:"vsc-start-pause-resume-rec,omitempty" bencode:"vsc-start-pause-resume-rec,omitempty"`
 	RtppFlags              string                 `json:"rtpp-flags,omitempty" bencode:"rtpp-flags,omitempty"`
+	Template               string                 `json:"template,omitempty" bencode:"template,omitempty"`
+	Interface              MediaInterface         `json:"interface,omitempty" bencode:"interface,omitempty"`
 	SdpAttr                *ParamsSdpAttrSections `json:"sdp-attr,omitempty" bencode:"sdp-attr,omitempty"`
+	Moh                    *ParamMoh              `json:"moh,omitempty" bencode:"moh,omitempty"`
 }
 
 // Parametros de comportamento tipo inteiro
 type ParamsOptInt struct {
-	TOS              int `json:"TOS,omitempty" bencode:"TOS,omitempty"`
-	DeleteDelay      int `json:"delete-delay,omitempty" bencode:"delete-delay,omitempty"`
-	DelayBuffer      int `json:"delay-buffer,omitempty" bencode:"delay-buffer,omitempty"`
-	Volume           int `json:"volume,omitempty" bencode:"volume,omitempty"`
-	TriggerEndTime   int `json:"trigger-end-time,omitempty" bencode:"trigger-end-time,omitempty"`
-	TriggerEndDigits int `json:"trigger-end-digits,omitempty" bencode:"trigger-end-digits,omitempty"`
-	DTMFDelay        int `json:"DTMF-delay,omitempty" bencode:"DTMF-delay,omitempty"`
-	Ptime            int `json:"ptime,omitempty" bencode:"ptime,omitempty"`
-	PtimeReverse     int `json:"ptime-reverse,omitempty" bencode:"ptime-reverse,omitempty"`
-	DbId             int `json:"db-id,omitempty" bencode:"db-id,omitempty"`
-	Duration         int `json:"duration,omitempty" bencode:"duration,omitempty"`
+	TOS              int      `json:"TOS,omitempty" bencode:"TOS,omitempty"`
+	DeleteDelay      int      `json:"delete-delay,omitempty" bencode:"delete-delay,omitempty"`
+	DelayBuffer      int      `json:"delay-buffer,omitempty" bencode:"delay-buffer,omitempty"`
+	Volume           int      `json:"volume,omitempty" bencode:"volume,omitempty"`
+	TriggerEndTime   int      `json:"trigger-end-time,omitempty" bencode:"trigger-end-time,omitempty"`
+	TriggerEndDigits int      `json:"trigger-end-digits,omitempty" bencode:"trigger-end-digits,omitempty"`
+	DTMFDelay        int      `json:"DTMF-delay,omitempty" bencode:"DTMF-delay,omitempty"`
+	Ptime            int      `json:"ptime,omitempty" bencode:"ptime,omitempty"`
+	PtimeReverse     int      `json:"ptime-reverse,omitempty" bencode:"ptime-reverse,omitempty"`
+	DbId             int      `json:"db-id,omitempty" bencode:"db-id,omitempty"`
+	Duration         int      `json:"duration,omitempty" bencode:"duration,omitempty"`
+	StartPos         int      `json:"start-pos,omitempty" bencode:"start-pos,omitempty"`
+	RepeatTimes      int      `json:"repeat-times,omitempty" bencode:"repeat-times,omitempty"`
+	RepeatDuration   int      `json:"repeat-duration,omitempty" bencode:"repeat-duration,omitempty"`
+	SSRC             SSRCList `json:"SSRC,omitempty" bencode:"SSRC,omitempty"`
 }
 
 // Parametros de comportamento tipo array separado por ','
@@ -116,12 +181,66 @@ type ParamsOptStringArray struct {
 	RtcpMux      []ParamRTCPMux `json:"rtcp-mux,omitempty" bencode:"rtcp-mux,omitempty"`
 	SDES         []SDES         `json:"SDES,omitempty" bencode:"SDES,omitempty"`
 	Supports     []string       `json:"supports,omitempty" bencode:"supports,omitempty"`
-	T38          []string       `json:"T38,omitempty" bencode:"T38,omitempty"`
+	T38          []string       `json:"T.38,omitempty" bencode:"T.38,omitempty"`
 	OSRTP        []OSRTP        `json:"OSRTP,omitempty" bencode:"OSRTP,omitempty"`
 	ReceivedFrom []string       `json:"received-from,omitempty" bencode:"received-from,omitempty"`
 	FromTags     []string       `json:"from-tags,omitempty" bencode:"from-tags,omitempty"`
 	Frequencies  []string       `json:"frequencies,omitempty" bencode:"frequencies,omitempty"`
 	Replace      []ParamReplace `json:"replace,omitempty" bencode:"replace,omitempty"`
+	Direction    []string       `json:"direction,omitempty" bencode:"direction,omitempty"`
+}
+
+// Parametros de musica em espera, somente uma origem (file, blob ou db-id) por vez
+type ParamMoh struct {
+	File       string     `json:"file,omitempty" bencode:"file,omitempty"`
+	Blob       string     `json:"blob,omitempty" bencode:"blob,omitempty"`
+	DbId       string     `json:"db-id,omitempty" bencode:"db-id,omitempty"`
+	Mode       string     `json:"mode,omitempty" bencode:"mode,omitempty"`
+	Connection Connection `json:"connection,omitempty" bencode:"connection,omitempty"`
+}
+
+// Codigos VSC discados na chamada para controlar a gravação ex: *97, os codigos vazios não são enviados
+type VscConfig struct {
+	StartRec            string
+	StopRec             string
+	PauseRec            string
+	StartStopRec        string
+	PauseResumeRec      string
+	StartPauseResumeRec string
+}
+
+// Parametros do codec opus gerados no codec-set, os valores zero usam o padrão do rtpengine
+type OpusOptions struct {
+	// Canais 1 ou 2, padrão 2
+	Channels int
+	// Bitrate em bits por segundo entre 6000 e 510000
+	Bitrate int
+	// Taxa maxima de reprodução do decoder entre 8000 e 48000
+	MaxPlaybackRate int
+	CBR             bool
+	UseInbandFEC    bool
+}
+
+// Interface logica do rtpengine, um nome ou o par [entrada, saida] usado na ponte entre interfaces
+type MediaInterface []string
+
+// Envia o nome unico como string e o par como lista
+func (m MediaInterface) MarshalBencode() ([]byte, error) {
+	if len(m) == 1 {
+		return bencode.Marshal(m[0])
+	}
+	return bencode.Marshal([]string(m))
+}
+
+// SSRC solicitado no comando, diferente do SSRC da resposta que traz os SSRC negociados e as estatisticas
+type SSRCList []uint32
+
+// Envia o SSRC unico como inteiro e varios como lista
+func (s SSRCList) MarshalBencode() ([]byte, error) {
+	if len(s) == 1 {
+		return bencode.Marshal(s[0])
+	}
+	return bencode.Marshal([]uint32(s))
 }
 
 // Parametros de manipulação de sessão
@@ -166,10 +285,8 @@ func (r *Engine) GetNG() int {
 
 // Abrir conexão com o proxy rtpengine
 func (r *Engine) Conn() (net.Conn, error) {
-	engine := r.ip.String() + ":" + fmt.Sprint(r.port)
-	conn, err := net.Dial(r.proto, engine)
+	conn, err := r.dial()
 	if err != nil {
-		fmt.Println(err.Error(), r.proto, engine)
 		return nil, err
 	}
 	r.con = conn
@@ -177,6 +294,186 @@ func (r *Engine) Conn() (net.Conn, error) {
 
 }
 
+// Abrir uma nova conexão com o proxy rtpengine sem substituir a conexão padrão
+func (r *Engine) dial() (net.Conn, error) {
+	if r.ipAlternativo != nil {
+		return r.dialDuplo()
+	}
+
+	engine := net.JoinHostPort(r.ip.String(), fmt.Sprint(r.port))
+	dialer := net.Dialer{Timeout: r.connectTimeout}
+	conn, err := dialer.Dial(r.proto, engine)
+	if err != nil {
+		fmt.Println(err.Error(), r.proto, engine)
+		return nil, err
+	}
+	return conn, nil
+}
+
+// Conecta no IPv4 e no IPv6 ao mesmo tempo e usa a primeira conexão estabelecida, a outra tentativa e cancelada
+// no UDP o dial não verifica o destino então a familia escolhida e a primeira que responde ao ping NG
+func (r *Engine) dialDuplo() (net.Conn, error) {
+	type tentativa struct {
+		conn net.Conn
+		err  error
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dialer := net.Dialer{Timeout: r.connectTimeout}
+	tentativas := make(chan tentativa, 2)
+	for _, ip := range []net.IP{r.ip, r.ipAlternativo} {
+		go func(engine string) {
+			conn, err := dialer.DialContext(ctx, r.proto, engine)
+			if err == nil && strings.HasPrefix(r.proto, "udp") {
+				if err = r.pingDial(ctx, conn); err != nil {
+					conn.Close()
+					conn = nil
+				}
+			}
+			tentativas <- tentativa{conn, err}
+		}(net.JoinHostPort(ip.String(), fmt.Sprint(r.port)))
+	}
+
+	var erros []error
+	for i := 0; i < 2; i++ {
+		t := <-tentativas
+		if t.err != nil {
+			erros = append(erros, t.err)
+			continue
+		}
+
+		if i == 0 {
+			// a tentativa perdedora pode conectar antes de ver o cancelamento
+			go func() {
+				if t := <-tentativas; t.conn != nil {
+					t.conn.Close()
+				}
+			}()
+		}
+		return t.conn, nil
+	}
+	return nil, errors.Join(erros...)
+}
+
+// Prazo do ping da escolha da familia no UDP quando o WithClientConnectTimeout não for definido
+const pingDialTimeout = 2 * time.Second
+
+// Envia o ping NG na conexão UDP e aguarda o pong, o cancelamento do contexto interrompe a espera
+func (r *Engine) pingDial(ctx context.Context, conn net.Conn) error {
+	timeout := r.connectTimeout
+	if timeout <= 0 {
+		timeout = pingDialTimeout
+	}
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	defer context.AfterFunc(ctx, func() { conn.SetReadDeadline(time.Now()) })()
+
+	cookie := r.GetCookie()
+	menssagem, err := EncodeComando(cookie, &RequestRtp{Command: string(Ping)})
+	if err != nil {
+		return err
+	}
+
+	if _, err := conn.Write(menssagem); err != nil {
+		return err
+	}
+
+	respostaRaw := make([]byte, 65536)
+	n, err := conn.Read(respostaRaw)
+	if err != nil {
+		return err
+	}
+
+	resposta, err := DecodeRespostaStrict(cookie, respostaRaw[:n])
+	if err != nil {
+		return err
+	}
+
+	if resposta.ResultType() != ResultPong && resposta.ResultType() != ResultOK {
+		return fmt.Errorf("resposta %q inesperada ao ping %s", resposta.Result, resposta.ErrorReason)
+	}
+	return conn.SetReadDeadline(time.Time{})
+}
+
+// Estrutura do comando sem o MarshalBencode usada na codificação
+type requestRtp RequestRtp
+
+// Codifica o comando sem as chaves vazias, os campos sem omitempty dos parametros embutidos
+// (from-tag, to-tag, call-id, transport-protocol e sdp) não são enviados vazios
+func (r *RequestRtp) MarshalBencode() ([]byte, error) {
+	campos, err := r.campos()
+	if err != nil {
+		return nil, err
+	}
+	return bencode.Marshal(campos)
+}
+
+// Chaves do comando com valor, as strings, listas e dicionarios vazios são removidos
+func (r *RequestRtp) campos() (map[string]interface{}, error) {
+	data, err := bencode.Marshal((*requestRtp)(r))
+	if err != nil {
+		return nil, err
+	}
+
+	campos := make(map[string]interface{})
+	if err := bencode.Unmarshal(data, &campos); err != nil {
+		return nil, err
+	}
+
+	for chave, valor := range campos {
+		switch v := valor.(type) {
+		case string:
+			if v == "" {
+				delete(campos, chave)
+			}
+		case []interface{}:
+			if len(v) == 0 {
+				delete(campos, chave)
+			}
+		case map[string]interface{}:
+			if len(v) == 0 {
+				delete(campos, chave)
+			}
+		}
+	}
+
+	if r.deleteImediato && r.ParamsOptInt != nil && r.DeleteDelay == 0 {
+		campos["delete-delay"] = 0
+	}
+	return campos, nil
+}
+
+// Chaves enviadas como string separada por ',' no formato legado
+var chavesLegado = []string{"flags", "replace", "rtcp-mux", "SDES", "OSRTP"}
+
+// Codifica o comando com as listas de flags, replace, rtcp-mux, SDES e OSRTP separadas por ',' usadas nos rtpengine antigos
+func EncodeComandoLegacy(cookie string, command *RequestRtp) ([]byte, error) {
+	campos, err := command.campos()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chave := range chavesLegado {
+		lista, ok := campos[chave].([]interface{})
+		if !ok {
+			continue
+		}
+
+		valores := make([]string, 0, len(lista))
+		for _, o := range lista {
+			valores = append(valores, fmt.Sprint(o))
+		}
+		campos[chave] = strings.Join(valores, ",")
+	}
+
+	data, err := bencode.Marshal(campos)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(cookie+" "), data...), nil
+}
+
 // Trasformar o comando em bencode
 func EncodeComando(cookie string, command *RequestRtp) ([]byte, error) {
 	data, err := bencode.Marshal(command)
@@ -190,26 +487,208 @@ func EncodeComando(cookie string, command *RequestRtp) ([]byte, error) {
 
 func DecodeResposta(cookie string, resposta []byte) *ResponseRtp {
 	resp := &ResponseRtp{}
-	cookieIndex := bytes.IndexAny(resposta, " ")
-	if cookieIndex != len(cookie) {
-		resp.Result = "error"
-		resp.ErrorReason = "Erro ao analisar a mensagem"
+	encodedData, err := separarCookie(cookie, resposta)
+	if err != nil {
+		resp.Result = string(ResultError)
+		resp.ErrorReason = err.Error()
 		return resp
 	}
 
-	cookieResponse := string(resposta[:cookieIndex])
-	if cookieResponse != cookie {
-		resp.Result = "error"
-		resp.ErrorReason = "O cookie não corresponde"
+	if err := bencode.Unmarshal(encodedData, resp); err != nil {
 		return resp
 	}
 
-	encodedData := string(resposta[cookieIndex+1:])
-	err := bencode.Unmarshal([]byte(encodedData), resp)
+	resp.Extra = chavesExtras(encodedData)
+	return resp
+}
 
+// Decodifica a resposta retornando o erro da mensagem mal formada ou do cookie diferente em vez de uma resposta vazia
+func DecodeRespostaStrict(cookie string, resposta []byte) (*ResponseRtp, error) {
+	encodedData, err := separarCookie(cookie, resposta)
 	if err != nil {
-		return resp
+		return nil, err
 	}
 
-	return resp
+	resp := &ResponseRtp{}
+	if err := bencode.Unmarshal(encodedData, resp); err != nil {
+		return nil, fmt.Errorf("erro ao decodificar a resposta: %w", err)
+	}
+
+	resp.Extra = chavesExtras(encodedData)
+	return resp, nil
+}
+
+// Valida o cookie no inicio da resposta e retorna o bencode que vem depois do espaço
+func separarCookie(cookie string, resposta []byte) ([]byte, error) {
+	cookieIndex := bytes.IndexByte(resposta, ' ')
+	if cookieIndex < 0 {
+		return nil, fmt.Errorf("malformed response: missing cookie delimiter")
+	}
+
+	if cookieIndex != len(cookie) || string(resposta[:cookieIndex]) != cookie {
+		return nil, fmt.Errorf("o cookie %q não corresponde", resposta[:cookieIndex])
+	}
+
+	if len(resposta) == cookieIndex+1 {
+		return nil, fmt.Errorf("resposta sem conteudo apos o cookie")
+	}
+	return resposta[cookieIndex+1:], nil
+}
+
+// Chaves da resposta decodificadas nos campos do ResponseRtp
+var chavesResposta = chavesBencode(reflect.TypeOf(ResponseRtp{}))
+
+// Lista as chaves bencode dos campos exportados da estrutura
+func chavesBencode(t reflect.Type) map[string]bool {
+	chaves := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		nome, _, _ := strings.Cut(field.Tag.Get("bencode"), ",")
+		if !field.IsExported() || nome == "-" {
+			continue
+		}
+
+		if nome == "" {
+			nome = field.Name
+		}
+		chaves[nome] = true
+	}
+	return chaves
+}
+
+// Retorna as chaves da resposta que o ResponseRtp ainda não modela
+func chavesExtras(data []byte) map[string]interface{} {
+	todas := make(map[string]interface{})
+	if err := bencode.Unmarshal(data, &todas); err != nil {
+		return nil
+	}
+
+	var extras map[string]interface{}
+	for chave, valor := range todas {
+		if chavesResposta[chave] {
+			continue
+		}
+
+		if extras == nil {
+			extras = make(map[string]interface{})
+		}
+		extras[chave] = valor
+	}
+	return extras
+}
+
+// Gera os parametros para um novo offer a partir do SDP retornado pelo rtpengine
+// usado em cadeias B2BUA onde o SDP de uma perna e a entrada da proxima
+func (r *ResponseRtp) ToOfferParams() *ParamsOptString {
+	parametros := &ParamsOptString{Sdp: r.Sdp}
+	if r.comando != nil && r.comando.ParamsOptString != nil {
+		parametros.CallId = r.comando.CallId
+		parametros.FromTag = r.comando.FromTag
+		parametros.ToTag = r.comando.ToTag
+	}
+	return parametros
+}
+
+// Quantidade de streams de midia ativos, considera ativo o stream com endpoint definido
+func (r *ResponseRtp) StreamCount() int {
+	total := 0
+	for _, tag := range r.Tags {
+		for _, media := range tag.Medias {
+			for _, stream := range media.Streams {
+				if stream.Endpoint.Port != 0 {
+					total++
+				}
+			}
+		}
+	}
+	return total
+}
+
+// Informações de um SSRC retornado pelo rtpengine
+type SSRCInfo struct {
+	Id    string                 `json:"SSRC"`
+	Stats map[string]interface{} `json:"stats,omitempty"`
+}
+
+// Lista os SSRC da resposta, o rtpengine pode retornar o SSRC como map ou como lista dependendo da versão
+func (r *ResponseRtp) SSRCs() []SSRCInfo {
+	ssrcs := make([]SSRCInfo, 0)
+	switch valor := r.SSRC.(type) {
+	case map[string]interface{}:
+		ids := make([]string, 0, len(valor))
+		for id := range valor {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		for _, id := range ids {
+			stats, _ := valor[id].(map[string]interface{})
+			ssrcs = append(ssrcs, SSRCInfo{Id: id, Stats: stats})
+		}
+	case []interface{}:
+		for _, item := range valor {
+			switch ssrc := item.(type) {
+			case map[string]interface{}:
+				ssrcs = append(ssrcs, SSRCInfo{Id: fmt.Sprint(ssrc["SSRC"]), Stats: ssrc})
+			case int64, string:
+				ssrcs = append(ssrcs, SSRCInfo{Id: fmt.Sprint(ssrc)})
+			}
+		}
+	}
+	return ssrcs
+}
+
+// Resultado da resposta tipado, valores desconhecidos retornam ResultUnknown
+func (r *ResponseRtp) ResultType() ResultType {
+	switch ResultType(r.Result) {
+	case ResultOK, ResultPong, ResultError:
+		return ResultType(r.Result)
+	}
+	return ResultUnknown
+}
+
+// Indica se o rtpengine informou um aviso, o comando foi executado mas com alguma limitação ex: codec sem transcodificação
+func (r *ResponseRtp) HasWarning() bool {
+	return r.Warning != ""
+}
+
+// Resposta recebida do rtpengine sem decodificar, disponivel somente com o WithClientKeepRaw
+func (r *ResponseRtp) Raw() []byte {
+	return r.raw
+}
+
+// Indica se o rtpengine encontrou a chamada do comando
+func (r *ResponseRtp) CallFound() bool {
+	return !unknownCall(r.ErrorReason) && !unknownCall(r.Warning)
+}
+
+// Data de criação da chamada combinando os segundos de created com os microssegundos de created_us
+func (r *ResponseRtp) CreatedTime() time.Time {
+	if r.Created == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(r.Created), int64(r.CreatedUs)*int64(time.Microsecond))
+}
+
+// Data da ultima sinalização da chamada
+func (r *ResponseRtp) LastSignalTime() time.Time {
+	return unixTime(r.LastSignal)
+}
+
+// Data da ultima atualização da chamada no redis
+func (r *ResponseRtp) LastRedisUpdateTime() time.Time {
+	return unixTime(r.LastRedisUpdate)
+}
+
+// Converte os segundos unix para time.Time, o valor ausente retorna o time.Time zero
+func unixTime(segundos int) time.Time {
+	if segundos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(segundos), 0)
+}
+
+// Verifica se a mensagem do rtpengine indica chamada não encontrada
+func unknownCall(mensagem string) bool {
+	return strings.Contains(strings.ToLower(mensagem), "unknown call")
 }
@@ -0,0 +1,220 @@
+package rtpengine
+
+import (
+	"testing"
+	"time"
+
+	bencode "github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequestStreamCount(t *testing.T) {
+	t.Run("Query gravado", func(t *testing.T) {
+		response := DecodeResposta("cookie", []byte("cookie "+queryDump))
+		require.Equal(t, "ok", response.Result)
+		require.Equal(t, 4, response.StreamCount())
+	})
+
+	t.Run("Stream sem endpoint", func(t *testing.T) {
+		data, err := bencode.Marshal(map[string]interface{}{
+			"result": "ok",
+			"tags": map[string]interface{}{
+				"tag1": map[string]interface{}{
+					"medias": []interface{}{
+						map[string]interface{}{"type": "audio", "streams": []interface{}{
+							map[string]interface{}{"local port": 30000, "endpoint": map[string]interface{}{"family": "IPv4", "address": "198.51.100.1", "port": 2000}},
+							map[string]interface{}{"local port": 30001},
+						}},
+						map[string]interface{}{"type": "video", "streams": []interface{}{
+							map[string]interface{}{"local port": 30002, "endpoint": map[string]interface{}{"family": "IPv4", "address": "198.51.100.1", "port": 2002}},
+						}},
+					},
+				},
+			},
+		})
+		require.Nil(t, err)
+		response := DecodeResposta("cookie", append([]byte("cookie "), data...))
+		require.Equal(t, 2, response.StreamCount())
+	})
+
+	t.Run("Sem tags", func(t *testing.T) {
+		require.Equal(t, 0, (&ResponseRtp{}).StreamCount())
+	})
+}
+
+func TestClientRequestSSRCs(t *testing.T) {
+	t.Run("Formato map", func(t *testing.T) {
+		response := DecodeResposta("cookie", []byte("cookie d4:SSRCd10:3405831957d7:packetsi120ee9:722931047d7:packetsi80eee6:result2:oke"))
+		ssrcs := response.SSRCs()
+		require.Len(t, ssrcs, 2)
+		require.Equal(t, "3405831957", ssrcs[0].Id)
+		require.Equal(t, int64(120), ssrcs[0].Stats["packets"])
+		require.Equal(t, "722931047", ssrcs[1].Id)
+	})
+
+	t.Run("Formato lista", func(t *testing.T) {
+		response := DecodeResposta("cookie", []byte("cookie d4:SSRCld4:SSRCi3405831957e7:packetsi120eei722931047ee6:result2:oke"))
+		ssrcs := response.SSRCs()
+		require.Len(t, ssrcs, 2)
+		require.Equal(t, "3405831957", ssrcs[0].Id)
+		require.Equal(t, int64(120), ssrcs[0].Stats["packets"])
+		require.Equal(t, "722931047", ssrcs[1].Id)
+		require.Nil(t, ssrcs[1].Stats)
+	})
+
+	t.Run("Sem SSRC", func(t *testing.T) {
+		require.Empty(t, (&ResponseRtp{SSRC: "invalido"}).SSRCs())
+	})
+}
+
+func TestClientRequestTotals(t *testing.T) {
+	response := DecodeResposta("cookie", []byte("cookie "+queryDump))
+	require.Equal(t, ValuesRTP{Packets: 240, Bytes: 38400}, response.Totals.Rtp)
+	require.Equal(t, ValuesRTP{Packets: 10, Bytes: 800}, response.Totals.Rtcp)
+}
+
+func TestClientRequestEncodeStartPos(t *testing.T) {
+	request := &RequestRtp{
+		Command:         string(PlayMedia),
+		ParamsOptString: &ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"},
+		ParamsOptInt:    &ParamsOptInt{StartPos: 5},
+	}
+
+	data, err := EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "9:start-posi5e")
+	require.NotContains(t, string(data), "rstart-pos")
+}
+
+func TestClientRequestTimestamps(t *testing.T) {
+	response := DecodeResposta("cookie", []byte("cookie d7:createdi1728899990e10:created_usi123456e11:last signali1728899995e17:last redis updatei1728899997e6:result2:oke"))
+	require.Equal(t, time.Unix(1728899990, 123456000), response.CreatedTime())
+	require.Equal(t, 123456*time.Microsecond, time.Duration(response.CreatedTime().Nanosecond()))
+	require.Equal(t, time.Unix(1728899995, 0), response.LastSignalTime())
+	require.Equal(t, time.Unix(1728899997, 0), response.LastRedisUpdateTime())
+
+	vazio := &ResponseRtp{}
+	require.True(t, vazio.CreatedTime().IsZero())
+	require.True(t, vazio.LastSignalTime().IsZero())
+	require.True(t, vazio.LastRedisUpdateTime().IsZero())
+}
+
+func TestClientRequestExtra(t *testing.T) {
+	response := DecodeResposta("cookie", []byte("cookie d9:new-field5:valor6:result2:ok7:warning5:aviso10:statisticsd5:callsi3eee"))
+	require.Equal(t, "ok", response.Result)
+	require.Equal(t, "aviso", response.Warning)
+	require.Equal(t, map[string]interface{}{
+		"new-field":  "valor",
+		"statistics": map[string]interface{}{"calls": int64(3)},
+	}, response.Extra)
+
+	response = DecodeResposta("cookie", []byte("cookie d6:result4:ponge"))
+	require.Nil(t, response.Extra)
+}
+
+func TestClientRequestResultType(t *testing.T) {
+	resultados := map[string]ResultType{
+		"ok":      ResultOK,
+		"pong":    ResultPong,
+		"error":   ResultError,
+		"load":    ResultUnknown,
+		"":        ResultUnknown,
+		"unknown": ResultUnknown,
+	}
+	for result, esperado := range resultados {
+		response := &ResponseRtp{Result: result}
+		require.Equal(t, esperado, response.ResultType(), result)
+		require.Equal(t, result, response.Result)
+	}
+}
+
+func TestClientRequestDecodeRespostaStrict(t *testing.T) {
+	t.Run("Resposta valida", func(t *testing.T) {
+		response, err := DecodeRespostaStrict("cookie", []byte("cookie d6:result2:ok7:warning5:avisoe"))
+		require.Nil(t, err)
+		require.Equal(t, ResultOK, response.ResultType())
+		require.Equal(t, "aviso", response.Warning)
+	})
+
+	t.Run("Resposta truncada", func(t *testing.T) {
+		response, err := DecodeRespostaStrict("cookie", []byte("cookie d6:result2:ok7:warn"))
+		require.NotNil(t, err)
+		require.Nil(t, response)
+
+		// a versão tolerante continua retornando a resposta
+		require.NotNil(t, DecodeResposta("cookie", []byte("cookie d6:result2:ok7:warn")))
+	})
+
+	t.Run("Resposta invalida", func(t *testing.T) {
+		response, err := DecodeRespostaStrict("cookie", []byte("cookie \x00\x01lixo"))
+		require.NotNil(t, err)
+		require.Nil(t, response)
+	})
+
+	t.Run("Cookie diferente", func(t *testing.T) {
+		response, err := DecodeRespostaStrict("cookie", []byte("cuckoo d6:result2:oke"))
+		require.NotNil(t, err)
+		require.Nil(t, response)
+	})
+}
+
+func TestClientRequestDecodeRespostaCookie(t *testing.T) {
+	t.Run("Sem espaço", func(t *testing.T) {
+		response := DecodeResposta("cookie", []byte("cookied6:result2:oke"))
+		require.Equal(t, ResultError, response.ResultType())
+		require.Equal(t, "malformed response: missing cookie delimiter", response.ErrorReason)
+
+		_, err := DecodeRespostaStrict("cookie", []byte("cookie"))
+		require.EqualError(t, err, "malformed response: missing cookie delimiter")
+	})
+
+	t.Run("Sem conteudo", func(t *testing.T) {
+		response := DecodeResposta("cookie", []byte("cookie "))
+		require.Equal(t, ResultError, response.ResultType())
+
+		_, err := DecodeRespostaStrict("cookie", []byte("cookie "))
+		require.NotNil(t, err)
+	})
+
+	t.Run("Resposta correta", func(t *testing.T) {
+		response := DecodeResposta("cookie", []byte("cookie d6:result4:ponge"))
+		require.Equal(t, ResultPong, response.ResultType())
+		require.Empty(t, response.ErrorReason)
+	})
+}
+
+func TestClientRequestHasWarning(t *testing.T) {
+	response := DecodeResposta("cookie", []byte("cookie d6:result2:ok7:warning30:Codec opus nao transcodificadoe"))
+	require.Equal(t, ResultOK, response.ResultType())
+	require.True(t, response.HasWarning())
+	require.Equal(t, "Codec opus nao transcodificado", response.Warning)
+
+	response = DecodeResposta("cookie", []byte("cookie d6:result2:oke"))
+	require.False(t, response.HasWarning())
+}
+
+func TestClientRequestEncodeSemChavesVazias(t *testing.T) {
+	ping := &RequestRtp{
+		Command:              string(Ping),
+		ParamsOptString:      &ParamsOptString{},
+		ParamsOptInt:         &ParamsOptInt{},
+		ParamsOptStringArray: &ParamsOptStringArray{},
+	}
+	data, err := EncodeComando("cookie", ping)
+	require.Nil(t, err)
+	require.Equal(t, "cookie d7:command4:pinge", string(data))
+	require.NotContains(t, string(data), "flags")
+	require.NotContains(t, string(data), "TOS")
+
+	request, err := SDPDelete(&ParamsOptString{CallId: "5464asdas", FromTag: "asdasdasd494894"})
+	require.Nil(t, err)
+	data, err = EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Equal(t, "cookie d7:call-id9:5464asdas7:command6:delete8:from-tag15:asdasdasd494894e", string(data))
+
+	// o delete-delay zero do SetDeleteDelay e mantido
+	require.Nil(t, request.SetDeleteDelay(0)(request))
+	data, err = EncodeComando("cookie", request)
+	require.Nil(t, err)
+	require.Contains(t, string(data), "12:delete-delayi0e")
+}
@@ -0,0 +1,253 @@
+package rtpengine
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Linhas obrigatorias no inicio do SDP na ordem definida pela RFC 4566
+var sdpCabecalho = []byte{'v', 'o', 's'}
+
+// Valida o SDP antes do envio para o rtpengine aceitando quebra de linha LF ou CRLF
+func ValidateSDP(sdp string) error {
+	if strings.TrimSpace(sdp) == "" {
+		return errors.New("SDP vazio")
+	}
+
+	cabecalho := 0
+	midias := 0
+	for i, linha := range strings.Split(sdp, "\n") {
+		linha = strings.TrimSuffix(linha, "\r")
+		if linha == "" {
+			continue
+		}
+
+		numero := i + 1
+		if len(linha) < 2 || linha[1] != '=' || linha[0] < 'a' || linha[0] > 'z' {
+			return fmt.Errorf("SDP linha %d: formato invalido %q", numero, linha)
+		}
+
+		tipo := linha[0]
+		if cabecalho < len(sdpCabecalho) {
+			if tipo != sdpCabecalho[cabecalho] {
+				return fmt.Errorf("SDP linha %d: esperado %c= mas encontrado %q", numero, sdpCabecalho[cabecalho], linha)
+			}
+			cabecalho++
+			continue
+		}
+
+		switch tipo {
+		case 'v', 'o', 's':
+			return fmt.Errorf("SDP linha %d: %c= fora de ordem %q", numero, tipo, linha)
+		case 'm':
+			midias++
+		}
+	}
+
+	if cabecalho < len(sdpCabecalho) {
+		return fmt.Errorf("SDP sem a linha obrigatoria %c=", sdpCabecalho[cabecalho])
+	}
+
+	if midias == 0 {
+		return errors.New("SDP sem a linha obrigatoria m=")
+	}
+	return nil
+}
+
+// Valida o SDP e atribui ao comando
+func (c *RequestRtp) WithValidatedSDP(sdp string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		if err := ValidateSDP(sdp); err != nil {
+			return err
+		}
+		s.Sdp = sdp
+		return nil
+	}
+}
+
+// Atribui o SDP ao comando convertendo as quebras de linha LF e CR para CRLF
+func (c *RequestRtp) SetSdp(sdp string) ParametrosOption {
+	return func(s *RequestRtp) error {
+		s.Sdp = NormalizeSDP(sdp)
+		return nil
+	}
+}
+
+// Converte todas as quebras de linha do SDP para CRLF, o SDP ja em CRLF não e alterado
+func NormalizeSDP(sdp string) string {
+	sdp = strings.ReplaceAll(sdp, "\r\n", "\n")
+	sdp = strings.ReplaceAll(sdp, "\r", "\n")
+	return strings.ReplaceAll(sdp, "\n", "\r\n")
+}
+
+// Endereço de midia alocado pelo rtpengine
+type MediaEndpoint struct {
+	IP    net.IP
+	Port  int
+	Proto string
+	Type  string
+}
+
+// Lista os endereços de midia do SDP retornado, usando o c= da midia ou o c= da sessão
+func (r *ResponseRtp) MediaEndpoints() ([]MediaEndpoint, error) {
+	if r.Sdp == "" {
+		return nil, errors.New("resposta sem SDP")
+	}
+
+	var sessao net.IP
+	endpoints := make([]MediaEndpoint, 0)
+	for i, linha := range strings.Split(r.Sdp, "\n") {
+		linha = strings.TrimSuffix(linha, "\r")
+		switch {
+		case strings.HasPrefix(linha, "m="):
+			campos := strings.Fields(linha[2:])
+			if len(campos) < 3 {
+				return nil, fmt.Errorf("SDP linha %d: m= invalido %q", i+1, linha)
+			}
+
+			port, err := strconv.Atoi(strings.Split(campos[1], "/")[0])
+			if err != nil {
+				return nil, fmt.Errorf("SDP linha %d: porta invalida %q", i+1, linha)
+			}
+			endpoints = append(endpoints, MediaEndpoint{IP: sessao, Port: port, Proto: campos[2], Type: campos[0]})
+		case strings.HasPrefix(linha, "c="):
+			campos := strings.Fields(linha[2:])
+			if len(campos) < 3 {
+				return nil, fmt.Errorf("SDP linha %d: c= invalido %q", i+1, linha)
+			}
+
+			ip := net.ParseIP(strings.Split(campos[2], "/")[0])
+			if ip == nil {
+				return nil, fmt.Errorf("SDP linha %d: endereço invalido %q", i+1, linha)
+			}
+
+			// c= antes das midias vale para todas as midias sem c= proprio
+			if len(endpoints) == 0 {
+				sessao = ip
+				continue
+			}
+			endpoints[len(endpoints)-1].IP = ip
+		}
+	}
+	return endpoints, nil
+}
+
+// Montador do SDP com o subconjunto de linhas usado pelo rtpengine
+type SDPBuilder struct {
+	origin     string
+	session    string
+	connection string
+	attributes []string
+	medias     []sdpMedia
+}
+
+type sdpMedia struct {
+	tipo       string
+	port       int
+	proto      string
+	codecs     []int
+	connection string
+	attributes []string
+}
+
+// Criar o montador do SDP com origem e nome de sessão padrão
+func NewSDPBuilder() *SDPBuilder {
+	return &SDPBuilder{
+		origin:  "- 0 0 IN IP4 0.0.0.0",
+		session: "-",
+	}
+}
+
+// Definir a linha o= do SDP
+func (b *SDPBuilder) SetOrigin(username string, sessionId, version uint64, ip string) *SDPBuilder {
+	if username == "" {
+		username = "-"
+	}
+	b.origin = fmt.Sprintf("%s %d %d IN %s", username, sessionId, version, sdpEndereco(ip))
+	return b
+}
+
+// Definir a linha s= do SDP
+func (b *SDPBuilder) SetSessionName(name string) *SDPBuilder {
+	b.session = name
+	return b
+}
+
+// Definir a linha c= da sessão, depois de uma midia a linha c= e da ultima midia adicionada
+func (b *SDPBuilder) SetConnection(ip string) *SDPBuilder {
+	if len(b.medias) > 0 {
+		b.medias[len(b.medias)-1].connection = "IN " + sdpEndereco(ip)
+		return b
+	}
+	b.connection = "IN " + sdpEndereco(ip)
+	return b
+}
+
+// Adicionar a linha a= na sessão ou na ultima midia adicionada
+func (b *SDPBuilder) AddAttribute(attribute string) *SDPBuilder {
+	if len(b.medias) > 0 {
+		b.medias[len(b.medias)-1].attributes = append(b.medias[len(b.medias)-1].attributes, attribute)
+		return b
+	}
+	b.attributes = append(b.attributes, attribute)
+	return b
+}
+
+// Adicionar a midia de audio RTP/AVP com os payload types informados
+func (b *SDPBuilder) AddAudioMedia(port int, codecs ...int) *SDPBuilder {
+	return b.AddMedia("audio", port, string(RTP_AVP), codecs...)
+}
+
+// Adicionar a midia com o tipo, porta, protocolo e payload types informados
+func (b *SDPBuilder) AddMedia(tipo string, port int, proto string, codecs ...int) *SDPBuilder {
+	b.medias = append(b.medias, sdpMedia{tipo: tipo, port: port, proto: proto, codecs: codecs})
+	return b
+}
+
+// Gerar o SDP na ordem da RFC 4566 com quebra de linha CRLF
+func (b *SDPBuilder) Build() string {
+	var sdp strings.Builder
+	linha := func(tipo byte, valor string) {
+		sdp.WriteByte(tipo)
+		sdp.WriteByte('=')
+		sdp.WriteString(valor)
+		sdp.WriteString("\r\n")
+	}
+
+	linha('v', "0")
+	linha('o', b.origin)
+	linha('s', b.session)
+	if b.connection != "" {
+		linha('c', b.connection)
+	}
+	linha('t', "0 0")
+	for _, attribute := range b.attributes {
+		linha('a', attribute)
+	}
+
+	for _, media := range b.medias {
+		formatos := make([]string, 0, len(media.codecs))
+		for _, codec := range media.codecs {
+			formatos = append(formatos, strconv.Itoa(codec))
+		}
+		linha('m', strings.TrimSpace(fmt.Sprintf("%s %d %s %s", media.tipo, media.port, media.proto, strings.Join(formatos, " "))))
+		if media.connection != "" {
+			linha('c', media.connection)
+		}
+		for _, attribute := range media.attributes {
+			linha('a', attribute)
+		}
+	}
+	return sdp.String()
+}
+
+// Tipo e endereço da linha c= e o= conforme a familia do IP
+func sdpEndereco(ip string) string {
+	if parsed := net.ParseIP(ip); parsed != nil && parsed.To4() == nil {
+		return "IP6 " + ip
+	}
+	return "IP4 " + ip
+}
@@ -0,0 +1,132 @@
+package rtpengine
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequestValidateSDP(t *testing.T) {
+	t.Run("SDP LF", func(t *testing.T) {
+		require.Nil(t, ValidateSDP(sdpTeste))
+	})
+
+	t.Run("SDP CRLF", func(t *testing.T) {
+		require.Nil(t, ValidateSDP(strings.ReplaceAll(sdpTeste, "\n", "\r\n")+"\r\n"))
+	})
+
+	linhas := map[string]string{
+		"v=": "v=0\n",
+		"o=": "o=- 1545997027 1 IN IP4 198.51.100.1\n",
+		"s=": "s=tester\n",
+		"m=": "m=audio 2000 RTP/AVP 0\n",
+	}
+	for linha, remover := range linhas {
+		t.Run("Sem "+linha, func(t *testing.T) {
+			err := ValidateSDP(strings.Replace(sdpTeste, remover, "", 1))
+			require.NotNil(t, err)
+			require.Contains(t, err.Error(), linha)
+		})
+	}
+
+	t.Run("Linha invalida", func(t *testing.T) {
+		err := ValidateSDP(strings.Replace(sdpTeste, "t=0 0", "t0 0", 1))
+		require.NotNil(t, err)
+		require.Contains(t, err.Error(), "linha 4")
+	})
+
+	t.Run("WithValidatedSDP", func(t *testing.T) {
+		opt := &RequestRtp{}
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas"}, opt.WithValidatedSDP(sdpTeste))
+		require.Nil(t, err)
+		require.Equal(t, sdpTeste, request.Sdp)
+
+		_, err = SDPOffering(&ParamsOptString{CallId: "5464asdas"}, opt.WithValidatedSDP("v=0"))
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestMediaEndpoints(t *testing.T) {
+	t.Run("Audio", func(t *testing.T) {
+		resposta := &ResponseRtp{Result: "ok", Sdp: strings.ReplaceAll(strings.NewReplacer(
+			"m=audio 2000", "m=audio 30000",
+			"c=IN IP4 198.51.100.1", "c=IN IP4 203.0.113.5",
+		).Replace(sdpTeste), "\n", "\r\n")}
+
+		endpoints, err := resposta.MediaEndpoints()
+		require.Nil(t, err)
+		require.Equal(t, []MediaEndpoint{{IP: net.ParseIP("203.0.113.5"), Port: 30000, Proto: "RTP/AVP", Type: "audio"}}, endpoints)
+	})
+
+	t.Run("Sessao e IPv6", func(t *testing.T) {
+		resposta := &ResponseRtp{Result: "ok", Sdp: "v=0\no=- 1 1 IN IP4 203.0.113.5\ns=-\nc=IN IP4 203.0.113.5\nt=0 0\n" +
+			"m=audio 30000 RTP/SAVPF 111\nm=video 30002 RTP/AVP 96\nc=IN IP6 2001:db8::5\n"}
+
+		endpoints, err := resposta.MediaEndpoints()
+		require.Nil(t, err)
+		require.Len(t, endpoints, 2)
+		require.Equal(t, "203.0.113.5", endpoints[0].IP.String())
+		require.Equal(t, "RTP/SAVPF", endpoints[0].Proto)
+		require.Equal(t, "video", endpoints[1].Type)
+		require.Equal(t, 30002, endpoints[1].Port)
+		require.Equal(t, "2001:db8::5", endpoints[1].IP.String())
+	})
+
+	t.Run("Sem SDP", func(t *testing.T) {
+		_, err := (&ResponseRtp{Result: "ok"}).MediaEndpoints()
+		require.NotNil(t, err)
+	})
+}
+
+func TestClientRequestSDPBuilder(t *testing.T) {
+	sdp := NewSDPBuilder().
+		SetOrigin("", 1545997027, 1, "198.51.100.1").
+		SetSessionName("tester").
+		SetConnection("198.51.100.1").
+		AddAudioMedia(2000, 0, 8, 101).
+		AddAttribute("rtpmap:101 telephone-event/8000").
+		AddAttribute("sendrecv").
+		AddMedia("video", 2002, string(RTP_AVP), 96).
+		SetConnection("2001:db8::1").
+		Build()
+
+	require.Nil(t, ValidateSDP(sdp))
+	require.True(t, strings.HasPrefix(sdp, "v=0\r\no=- 1545997027 1 IN IP4 198.51.100.1\r\ns=tester\r\nc=IN IP4 198.51.100.1\r\nt=0 0\r\n"))
+	require.Contains(t, sdp, "\r\nm=audio 2000 RTP/AVP 0 8 101\r\na=rtpmap:101 telephone-event/8000\r\na=sendrecv\r\n")
+	require.Contains(t, sdp, "\r\nm=video 2002 RTP/AVP 96\r\nc=IN IP6 2001:db8::1\r\n")
+	require.Equal(t, 0, strings.Count(strings.ReplaceAll(sdp, "\r\n", ""), "\n"))
+
+	endpoints, err := (&ResponseRtp{Sdp: sdp}).MediaEndpoints()
+	require.Nil(t, err)
+	require.Len(t, endpoints, 2)
+	require.Equal(t, "198.51.100.1", endpoints[0].IP.String())
+	require.Equal(t, "2001:db8::1", endpoints[1].IP.String())
+
+	opt := &RequestRtp{}
+	request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas"}, opt.WithValidatedSDP(sdp))
+	require.Nil(t, err)
+	require.Equal(t, sdp, request.Sdp)
+}
+
+func TestClientRequestSetSdp(t *testing.T) {
+	opt := &RequestRtp{}
+	crlf := strings.ReplaceAll(sdpTeste, "\n", "\r\n")
+
+	t.Run("LF", func(t *testing.T) {
+		request, err := SDPOffering(&ParamsOptString{CallId: "5464asdas"}, opt.SetSdp(sdpTeste))
+		require.Nil(t, err)
+		require.Equal(t, crlf, request.Sdp)
+	})
+
+	t.Run("CRLF", func(t *testing.T) {
+		require.Equal(t, crlf, NormalizeSDP(crlf))
+		require.Equal(t, crlf, NormalizeSDP(NormalizeSDP(sdpTeste)))
+	})
+
+	t.Run("Misto", func(t *testing.T) {
+		misto := strings.Replace(strings.Replace(crlf, "\r\n", "\n", 2), "\r\n", "\r", 1)
+		require.Equal(t, crlf, NormalizeSDP(misto))
+	})
+}
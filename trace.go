@@ -0,0 +1,61 @@
+package rtpengine
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Linha do trace com o comando enviado e a resposta decodificada
+type traceLine struct {
+	Time     time.Time    `json:"time"`
+	Cookie   string       `json:"cookie"`
+	Command  *RequestRtp  `json:"command"`
+	Duration string       `json:"duration"`
+	Response *ResponseRtp `json:"response,omitempty"`
+	Warning  string       `json:"warning,omitempty"`
+	Error    string       `json:"error,omitempty"`
+}
+
+// Grava cada comando como um objeto JSON por linha
+type tracer struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// WithClientTrace Grava cada comando e resposta do client em JSON, um objeto por linha
+// nada e removido do trace, o SDP e os parametros da chamada podem conter informações sensiveis
+func WithClientTrace(w io.Writer) ClientOption {
+	return func(s *Client) error {
+		s.tracer = &tracer{w: w}
+		return nil
+	}
+}
+
+// Escreve a linha do comando no trace
+func (t *tracer) gravar(cookie string, comando *RequestRtp, resposta *ResponseRtp, err error, latencia time.Duration) error {
+	linha := traceLine{
+		Time:     time.Now(),
+		Cookie:   cookie,
+		Command:  comando,
+		Duration: latencia.String(),
+		Response: resposta,
+	}
+	if err != nil {
+		linha.Error = err.Error()
+	}
+	if resposta != nil && resposta.HasWarning() {
+		linha.Warning = resposta.Warning
+	}
+
+	data, err := json.Marshal(linha)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	_, err = t.w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,53 @@
+package rtpengine
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestClientRequestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	}, WithClientTrace(&buf))
+
+	_, err := client.Ping()
+	require.Nil(t, err)
+	_, err = client.Ping()
+	require.Nil(t, err)
+
+	linhas := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		linha := make(map[string]interface{})
+		require.Nil(t, json.Unmarshal(scanner.Bytes(), &linha))
+		require.NotEmpty(t, linha["cookie"])
+		require.Equal(t, "ping", linha["command"].(map[string]interface{})["command"])
+		require.Equal(t, "pong", linha["response"].(map[string]interface{})["result"])
+
+		_, err := time.Parse(time.RFC3339Nano, linha["time"].(string))
+		require.Nil(t, err)
+		_, err = time.ParseDuration(linha["duration"].(string))
+		require.Nil(t, err)
+		linhas++
+	}
+	require.Equal(t, 2, linhas)
+}
+
+func TestClientRequestTraceWarning(t *testing.T) {
+	var buf bytes.Buffer
+	client := newMockClient(t, func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "ok", "warning": "Codec opus nao transcodificado"})
+	}, WithClientTrace(&buf))
+
+	require.NotNil(t, client.NewComando(&RequestRtp{Command: string(Ping)}))
+
+	linha := make(map[string]interface{})
+	require.Nil(t, json.Unmarshal(bytes.TrimSpace(buf.Bytes()), &linha))
+	require.Equal(t, "Codec opus nao transcodificado", linha["warning"])
+}
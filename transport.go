@@ -0,0 +1,132 @@
+package rtpengine
+
+import (
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// Transporte das mensagens NG entre o client e o rtpengine, permite substituir a rede nos testes
+// sem o WithClientTransport o client usa diretamente a conexão UDP ou TCP aberta com o rtpengine
+type Transport interface {
+	// Envia a mensagem com o cookie e o comando em bencode
+	Send([]byte) error
+	// Recebe a proxima mensagem com o cookie e a resposta em bencode
+	Receive() ([]byte, error)
+	Close() error
+}
+
+// WithClientTransport Permite usar um transporte proprio no lugar da conexão de rede, o transporte não e reaberto no Reset
+func WithClientTransport(transport Transport) ClientOption {
+	return func(s *Client) error {
+		s.transport = transport
+		return nil
+	}
+}
+
+// Conexão usada pelo client sobre o transporte informado no WithClientTransport, o prazo de leitura
+// e aplicado mesmo quando o Receive do transporte bloqueia e a mensagem maior que o buffer e lida em partes
+type transportConn struct {
+	Transport
+	mu        sync.Mutex
+	prazo     time.Time
+	recebendo chan recebido
+	restante  []byte
+}
+
+// Mensagem recebida pelo Receive do transporte
+type recebido struct {
+	menssagem []byte
+	err       error
+}
+
+// Endereço do transporte sem rede
+type transportAddr struct{}
+
+func (transportAddr) Network() string { return "transport" }
+func (transportAddr) String() string  { return "transport" }
+
+func (c *transportConn) Read(b []byte) (int, error) {
+	c.mu.Lock()
+	if len(c.restante) > 0 {
+		n := copy(b, c.restante)
+		c.restante = c.restante[n:]
+		c.mu.Unlock()
+		return n, nil
+	}
+
+	// o Receive que passou do prazo continua aguardando e a mensagem fica para a proxima leitura
+	if c.recebendo == nil {
+		recebendo := make(chan recebido, 1)
+		c.recebendo = recebendo
+		go func() {
+			menssagem, err := c.Receive()
+			recebendo <- recebido{menssagem, err}
+		}()
+	}
+	recebendo, prazo := c.recebendo, c.prazo
+	c.mu.Unlock()
+
+	var expirou <-chan time.Time
+	if !prazo.IsZero() {
+		timer := time.NewTimer(time.Until(prazo))
+		defer timer.Stop()
+		expirou = timer.C
+	}
+
+	select {
+	case r := <-recebendo:
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.recebendo = nil
+		if r.err != nil {
+			return 0, r.err
+		}
+		n := copy(b, r.menssagem)
+		c.restante = r.menssagem[n:]
+		return n, nil
+	case <-expirou:
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *transportConn) Write(b []byte) (int, error) {
+	if err := c.Send(b); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (c *transportConn) LocalAddr() net.Addr  { return transportAddr{} }
+func (c *transportConn) RemoteAddr() net.Addr { return transportAddr{} }
+
+// O prazo de leitura e aplicado no Read, os prazos também são repassados quando o transporte implementa os metodos da net.Conn
+func (c *transportConn) SetDeadline(t time.Time) error {
+	c.definirPrazo(t)
+	if d, ok := c.Transport.(interface{ SetDeadline(time.Time) error }); ok {
+		return d.SetDeadline(t)
+	}
+	return nil
+}
+
+func (c *transportConn) SetReadDeadline(t time.Time) error {
+	c.definirPrazo(t)
+	if d, ok := c.Transport.(interface{ SetReadDeadline(time.Time) error }); ok {
+		return d.SetReadDeadline(t)
+	}
+	return nil
+}
+
+func (c *transportConn) SetWriteDeadline(t time.Time) error {
+	if d, ok := c.Transport.(interface{ SetWriteDeadline(time.Time) error }); ok {
+		return d.SetWriteDeadline(t)
+	}
+	return nil
+}
+
+func (c *transportConn) definirPrazo(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.prazo = t
+}
@@ -0,0 +1,123 @@
+package rtpengine
+
+import (
+	"bytes"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	bencode "github.com/anacrolix/torrent/bencode"
+	"github.com/stretchr/testify/require"
+)
+
+// Transporte de teste sem rede, grava as mensagens enviadas e entrega as respostas do handler
+type fakeTransport struct {
+	mu        sync.Mutex
+	enviados  [][]byte
+	respostas chan []byte
+	fechado   bool
+	handler   func(cookie string, comando map[string]interface{}) []byte
+}
+
+func newFakeTransport(handler func(cookie string, comando map[string]interface{}) []byte) *fakeTransport {
+	return &fakeTransport{respostas: make(chan []byte, 16), handler: handler}
+}
+
+func (f *fakeTransport) Send(menssagem []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.fechado {
+		return net.ErrClosed
+	}
+	f.enviados = append(f.enviados, append([]byte(nil), menssagem...))
+
+	cookie, data, _ := bytes.Cut(menssagem, []byte(" "))
+	comando := make(map[string]interface{})
+	bencode.Unmarshal(data, &comando)
+	if resposta := f.handler(string(cookie), comando); resposta != nil {
+		f.respostas <- resposta
+	}
+	return nil
+}
+
+func (f *fakeTransport) Receive() ([]byte, error) {
+	resposta, ok := <-f.respostas
+	if !ok {
+		return nil, net.ErrClosed
+	}
+	return resposta, nil
+}
+
+func (f *fakeTransport) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.fechado {
+		f.fechado = true
+		close(f.respostas)
+	}
+	return nil
+}
+
+func TestClientRequestWithClientTransport(t *testing.T) {
+	transport := newFakeTransport(func(cookie string, comando map[string]interface{}) []byte {
+		return mockResposta(cookie, map[string]interface{}{"result": "pong"})
+	})
+	client, err := NewClient(&Engine{}, WithClientTransport(transport))
+	require.Nil(t, err)
+
+	_, err = client.Ping()
+	require.Nil(t, err)
+	require.Len(t, transport.enviados, 1)
+	require.True(t, bytes.HasSuffix(transport.enviados[0], []byte(" d7:command4:pinge")))
+
+	require.Nil(t, client.Reset())
+	require.Nil(t, client.Close())
+	require.True(t, transport.fechado)
+
+	_, err = NewClient(&Engine{}, WithClientTransport(transport), WithClientPoolSize(2))
+	require.NotNil(t, err)
+}
+
+func TestClientRequestTransportTimeout(t *testing.T) {
+	// o transporte não responde e o Receive fica bloqueado
+	transport := newFakeTransport(func(cookie string, comando map[string]interface{}) []byte { return nil })
+	client, err := NewClient(&Engine{}, WithClientTransport(transport))
+	require.Nil(t, err)
+	defer client.Close()
+	client.timeout = 50 * time.Millisecond
+
+	inicio := time.Now()
+	_, err = client.Ping()
+	require.NotNil(t, err)
+	require.True(t, timeoutErr(err))
+	require.Less(t, time.Since(inicio), time.Second)
+
+	// a resposta atrasada e entregue na proxima leitura
+	transport.respostas <- []byte("atrasada")
+	conn := client.con
+	require.Nil(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 16)
+	n, err := conn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "atrasada", string(buf[:n]))
+}
+
+func TestClientRequestTransportMensagemGrande(t *testing.T) {
+	transport := newFakeTransport(nil)
+	conn := &transportConn{Transport: transport}
+	transport.respostas <- []byte("cookie d6:result4:ponge")
+
+	buf := make([]byte, 10)
+	n, err := conn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "cookie d6:", string(buf[:n]))
+
+	n, err = conn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "result4:po", string(buf[:n]))
+
+	n, err = conn.Read(buf)
+	require.Nil(t, err)
+	require.Equal(t, "nge", string(buf[:n]))
+}
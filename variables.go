@@ -12,6 +12,11 @@ const (
 	UDP_TLS_RTP_SAVPF TransportProtocol = "UDP/TLS/RTP/SAVPF"
 )
 
+// Lista dos protocolos de transporte aceitos
+func ValidTransportProtocols() []TransportProtocol {
+	return []TransportProtocol{RTP_AVP, RTP_SAVP, RTP_AVPF, RTP_SAVPF, UDP_TLS_RTP_SAVP, UDP_TLS_RTP_SAVPF}
+}
+
 // Definição dos comandos aceitos
 
 type TipoComandos string
@@ -42,6 +47,7 @@ const (
 	SubscribeRequest TipoComandos = "subscribe request"
 	SubscribeAnswer  TipoComandos = "subscribe answer"
 	Unsubscribe      TipoComandos = "unsubscribe"
+	Version          TipoComandos = "version"
 )
 
 // Definição dos tipo dtls
@@ -86,7 +92,8 @@ const (
 	SessionName              ParamReplace = "session-name"
 	ZeroAddress              ParamReplace = "zero-address"
 	ForceIncrementSdpVersion ParamReplace = "force-increment-sdp-version"
-	ForceIncrementSdpVer     ParamReplace = "force-increment-sdp-ver"
+	// Alias do ForceIncrementSdpVersion, o SetReplace envia o valor canonico
+	ForceIncrementSdpVer ParamReplace = "force-increment-sdp-ver"
 )
 
 // Tipo de parametros usado como flags
@@ -158,6 +165,7 @@ const (
 	CodecStripG723        ParamFlags = "codec-strip-G723"
 	CodecStripILBC        ParamFlags = "codec-strip-iLBC"
 	CodecStripSpeex       ParamFlags = "codec-strip-speex"
+	CodecStripAll         ParamFlags = "codec-strip-all"
 	CodecMaskPCMA         ParamFlags = "codec-mask-PCMA"
 	CodecMaskG729         ParamFlags = "codec-mask-G729"
 	CodecMaskG729a        ParamFlags = "codec-mask-G729a"
@@ -166,6 +174,7 @@ const (
 	CodecMaskG723         ParamFlags = "codec-mask-G723"
 	CodecMaskILBC         ParamFlags = "codec-mask-iLBC"
 	CodecMaskSpeex        ParamFlags = "codec-mask-speex"
+	CodecMaskAll          ParamFlags = "codec-mask-all"
 	CodecTranscodePCMA    ParamFlags = "codec-transcode-PCMA"
 	CodecTranscodeG729    ParamFlags = "codec-transcode-G729"
 	CodecTranscodeG729a   ParamFlags = "codec-transcode-G729a"
@@ -298,6 +307,22 @@ const (
 	OSRTPAccept       OSRTP = "accept"
 )
 
+// Tipo T.38 string
+type T38 string
+
+const (
+	T38Decode   T38 = "decode"
+	T38Force    T38 = "force"
+	T38Stop     T38 = "stop"
+	T38NoECM    T38 = "no-ECM"
+	T38NoV17    T38 = "no-V.17"
+	T38NoV27ter T38 = "no-V.27ter"
+	T38NoV29    T38 = "no-V.29"
+	T38NoV34    T38 = "no-V.34"
+	T38NoIAF    T38 = "no-IAF"
+	T38FEC      T38 = "FEC"
+)
+
 // Tipo Address Family string
 type AddressFamily string
 
@@ -305,3 +330,33 @@ const (
 	AddressFamilyIP4 AddressFamily = "IP4"
 	AddressFamilyIP6 AddressFamily = "IP6"
 )
+
+// Tipo ResultType string com o resultado da resposta do rtpengine
+type ResultType string
+
+const (
+	ResultOK    ResultType = "ok"
+	ResultPong  ResultType = "pong"
+	ResultError ResultType = "error"
+	// Resultado não reconhecido pelo pacote, o valor original continua em ResponseRtp.Result
+	ResultUnknown ResultType = "unknown"
+)
+
+// Tipo NATMode string com os conjuntos de flags de travessia de NAT
+type NATMode string
+
+const (
+	// Endpoint atras de NAT: aprende o endereço pelos pacotes recebidos e abre o pinhole
+	NATBehind NATMode = "behind-nat"
+	// Endpoint sem NAT: confia no endereço do SDP
+	NATTrusted NATMode = "trusted"
+	// Endpoint atras de NAT aceitando midia somente do endereço aprendido
+	NATStrict NATMode = "strict"
+)
+
+// Tipo Connection da musica em espera string
+type Connection string
+
+const (
+	MohConnection Connection = "zero"
+)